@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/plugin"
+	"github.com/ghodss/yaml"
+	"github.com/spf13/cobra"
+)
+
+var pluginKind string
+var pluginConfigFile string
+var pluginDir string
+var pluginTimeout string
+
+// pluginTestCmd runs a plugin outside of a test, for plugin authors
+var pluginTestCmd = &cobra.Command{
+	Use:   "plugin-test <plugin>",
+	Short: "Test a plugin by sending it the requests of the validator",
+	Long:  `Start a plugin by name (backup-validator-plugin-<name> in the PATH) or path, and send it the requests the validator sends for the kind of plugin`,
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		config := plugin.Config{
+			Name: args[0],
+		}
+		if pluginTimeout != "" {
+			config.Timeout = &pluginTimeout
+		}
+		if pluginConfigFile != "" {
+			bytes, err := ioutil.ReadFile(pluginConfigFile)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			err = yaml.Unmarshal(bytes, &config.Config)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		err := os.MkdirAll(pluginDir, os.ModePerm)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		err = plugin.TestPlugin(pluginKind, config, pluginDir)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pluginTestCmd)
+	pluginTestCmd.Flags().StringVarP(&pluginKind, "kind", "k", "assert", "Kind of plugin. One of: \"assert\", \"format\" or \"backup\".")
+	pluginTestCmd.Flags().StringVarP(&pluginConfigFile, "config", "", "", "Yaml file with the plugin config.")
+	pluginTestCmd.Flags().StringVarP(&pluginDir, "dir", "d", ".backup-validator-plugin-test", "Directory to restore and import in.")
+	pluginTestCmd.Flags().StringVarP(&pluginTimeout, "timeout", "", "", "Max time the plugin may take to answer a request, eg. 30m. (default: 1h)")
+}
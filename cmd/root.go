@@ -19,8 +19,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/MaxxtonGroup/backup-validator/pkg/assert"
 	"github.com/MaxxtonGroup/backup-validator/pkg/report"
 	"github.com/MaxxtonGroup/backup-validator/pkg/validator"
 
@@ -31,6 +33,7 @@ var configFiles []string = []string{}
 var cleanup bool
 var reportFile string
 var reportFormat string
+var historyFile string
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -45,8 +48,23 @@ var rootCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		// Load the results of the previous run to compare metrics with
+		if historyFile == "" && reportFormat == "json" {
+			historyFile = reportFile
+		}
+		previousResults := []*validator.TestResult{}
+		if historyFile != "" {
+			if _, err := os.Stat(historyFile); err == nil {
+				err, previousResults = report.LoadJsonReport(historyFile)
+				if err != nil {
+					log.Printf("Failed to load history from json report '%s': %s", historyFile, err)
+					os.Exit(1)
+				}
+			}
+		}
+
 		// Execute command
-		testResults, err := validator.Validate(configFiles, cleanup)
+		testResults, err := validator.Validate(configFiles, cleanup, previousResults)
 		if err != nil {
 			fmt.Println(err)
 			os.Exit(1)
@@ -58,6 +76,23 @@ var rootCmd = &cobra.Command{
 		log.Println("Test result:")
 		for _, testResult := range testResults {
 			log.Printf("- %s (total: %s, restore: %s, import: %s):", testResult.Name, testResult.TotalDuration.Round(time.Second), testResult.RestoreDuration.Round(time.Second), testResult.ImportDuration.Round(time.Second))
+			if testResult.RecoveryPoint != nil && testResult.RecoveryPoint.Time != nil {
+				log.Printf("    recovered until: %s", testResult.RecoveryPoint.Time.Format(time.RFC3339))
+			}
+			if testResult.Snapshot != nil {
+				log.Printf("    snapshot: %s", *testResult.Snapshot)
+			}
+			if len(testResult.RestoredDatabases) > 0 {
+				log.Printf("    restored: %s", strings.Join(testResult.RestoredDatabases, ", "))
+			}
+			if testResult.ImportErrors != nil {
+				log.Printf("    import errors: %d", *testResult.ImportErrors)
+			}
+			for _, assertResult := range testResult.AssertResults {
+				if assertResult.Status == assert.StatusWarn {
+					log.Printf("    assert warning: %s", assertResult)
+				}
+			}
 			if testResult.Error != nil {
 				failedTests++
 				log.Printf("    error: %s\n", *testResult.Error)
@@ -120,6 +155,7 @@ func init() {
 	rootCmd.Flags().BoolVarP(&cleanup, "cleanup", "c", true, "Cleanup backup files after test has finished.")
 	rootCmd.Flags().StringVarP(&reportFile, "report-file", "o", "report.json", "Output file for the test results.")
 	rootCmd.Flags().StringVarP(&reportFormat, "report-format", "", "json", "Format of the test results. One of: \"json\" or \"html\".")
+	rootCmd.Flags().StringVarP(&historyFile, "history-file", "", "", "Json report of a previous run to compare measurements with. (default: --report-file when using the json format)")
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/schema"
+	"github.com/MaxxtonGroup/backup-validator/pkg/validator"
+	"github.com/spf13/cobra"
+)
+
+var schemaFile string
+var schemaTest string
+var schemaDatabase string
+
+// schemaSnapshotCmd restores a backup and stores its schema as golden file for the schemaMatches assert
+var schemaSnapshotCmd = &cobra.Command{
+	Use:   "schema-snapshot",
+	Short: "Generate a schema file for the schemaMatches assert from a restored backup",
+	Long:  `Restore the latest backup of a test and store the schema of its databases, which the schemaMatches assert compares later backups with`,
+	Run: func(cmd *cobra.Command, args []string) {
+		snapshot, err := validator.SnapshotSchema(configFiles, schemaTest, schemaDatabase, cleanup)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		err = schema.Store(schemaFile, snapshot)
+		if err != nil {
+			log.Printf("Failed to store schema file: %s", err)
+			os.Exit(1)
+		}
+		log.Printf("Stored the schema of %d databases in %s", len(snapshot.Databases), schemaFile)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(schemaSnapshotCmd)
+	schemaSnapshotCmd.Flags().StringSliceVarP(&configFiles, "test-file", "f", []string{}, "Test definition files.")
+	schemaSnapshotCmd.Flags().BoolVarP(&cleanup, "cleanup", "c", true, "Cleanup backup files after the schema is stored.")
+	schemaSnapshotCmd.Flags().StringVarP(&schemaTest, "test", "t", "", "Name of the test to restore. (default: the only test in the test files)")
+	schemaSnapshotCmd.Flags().StringVarP(&schemaDatabase, "database", "d", "*", "Databases to store the schema of, may contain '*' wildcards or be a /regex/.")
+	schemaSnapshotCmd.Flags().StringVarP(&schemaFile, "output", "o", "schema.yaml", "Output file for the schema.")
+}
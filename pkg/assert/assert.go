@@ -1,6 +1,7 @@
 package assert
 
 import (
+	"strings"
 	"time"
 
 	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
@@ -13,7 +14,117 @@ type Timings struct {
 }
 
 type Assert interface {
+	// Type is the name of the assert in the test definition, eg. tablesExists
+	Type() string
+
 	RunFor(assertConfig *AssertConfig) bool
 
-	Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot) *string
+	Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result
+}
+
+type Status string
+
+const (
+	StatusPass  Status = "pass"
+	StatusFail  Status = "fail"
+	StatusWarn  Status = "warn"
+	StatusSkip  Status = "skip"
+	StatusError Status = "error"
+)
+
+// statusOrder ranks the statuses from good to bad, the worst status of the details is the status of the result
+var statusOrder = map[Status]int{
+	StatusSkip:  0,
+	StatusPass:  1,
+	StatusWarn:  2,
+	StatusFail:  3,
+	StatusError: 4,
+}
+
+// Result is the outcome of an assert, an error means the assert couldn't check the backup (eg. an invalid config or a failing query)
+type Result struct {
+	Type     string        `json:"type"`
+	Status   Status        `json:"status"`
+	Severity string        `json:"severity,omitempty"`
+	Message  string        `json:"message,omitempty"`
+	Expected string        `json:"expected,omitempty"`
+	Actual   string        `json:"actual,omitempty"`
+	Details  []*Detail     `json:"details,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Detail is the outcome of an assert for a single database (or index, table or metric)
+type Detail struct {
+	Database string `json:"database"`
+	Status   Status `json:"status"`
+	Message  string `json:"message,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+func Pass() *Result {
+	return &Result{Status: StatusPass}
+}
+
+func Fail(message string) *Result {
+	return &Result{Status: StatusFail, Message: message}
+}
+
+func Skip(message string) *Result {
+	return &Result{Status: StatusSkip, Message: message}
+}
+
+func Error(err error) *Result {
+	return &Result{Status: StatusError, Message: err.Error()}
+}
+
+// WithValues sets the expected and the actual value of the result
+func (r *Result) WithValues(expected string, actual string) *Result {
+	r.Expected = expected
+	r.Actual = actual
+	return r
+}
+
+// Failed checks if the result should fail the test
+func (r *Result) Failed() bool {
+	return r.Status == StatusFail || r.Status == StatusError
+}
+
+// ResultOf combines the outcome per database, the message lists the databases that didn't pass
+func ResultOf(details []*Detail, message string) *Result {
+	result := &Result{
+		Status:  StatusSkip,
+		Details: details,
+	}
+	problems := []string{}
+	for _, detail := range details {
+		if statusOrder[detail.Status] > statusOrder[result.Status] {
+			result.Status = detail.Status
+		}
+		if detail.Status != StatusPass && detail.Status != StatusSkip {
+			problems = append(problems, detail.Database+": "+detail.Message)
+		}
+	}
+	if len(problems) > 0 {
+		result.Message = message + ": " + strings.Join(problems, ", ")
+	}
+	return result
+}
+
+// AnyOf passes when one of the databases passes, otherwise the details are combined like ResultOf
+func AnyOf(details []*Detail, message string) *Result {
+	for _, detail := range details {
+		if detail.Status == StatusPass {
+			return &Result{Status: StatusPass, Details: details}
+		}
+	}
+	return ResultOf(details, message)
+}
+
+// String formats the result for the log and the reports, eg. "tablesExists (fail): Missing tables: shop: orders"
+func (r *Result) String() string {
+	if r.Message == "" {
+		return r.Type + " (" + string(r.Status) + ")"
+	}
+	return r.Type + " (" + string(r.Status) + "): " + r.Message
 }
@@ -11,29 +11,30 @@ import (
 type BackupRetentionAssert struct {
 }
 
+func (a BackupRetentionAssert) Type() string {
+	return "backupRetention"
+}
+
 func (a BackupRetentionAssert) RunFor(assert *AssertConfig) bool {
 	return assert.BackupRetention != nil
 }
 
-func (a BackupRetentionAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot) *string {
+func (a BackupRetentionAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
 	snapshots, err := backupProvider.ListSnapshots(testName, dir)
 	if err != nil {
-		msg := err.Error()
-		return &msg
+		return Error(err)
 	}
 
 	if assertConfig.BackupRetention.Snapshots != nil {
 		if len(snapshots) < *assertConfig.BackupRetention.Snapshots {
-			msg := fmt.Sprintf("There are only %d snapshots available", len(snapshots))
-			return &msg
+			return Fail(fmt.Sprintf("There are only %d snapshots available", len(snapshots))).WithValues(fmt.Sprintf(">= %d snapshots", *assertConfig.BackupRetention.Snapshots), fmt.Sprintf("%d snapshots", len(snapshots)))
 		}
 	}
 
 	if assertConfig.BackupRetention.OlderThan != nil {
 		duration, err := time.ParseDuration(*assertConfig.BackupRetention.OlderThan)
 		if err != nil {
-			msg := err.Error()
-			return &msg
+			return Error(err)
 		}
 
 		var oldestSnapshot *time.Time
@@ -43,18 +44,16 @@ func (a BackupRetentionAssert) Run(testName string, dir string, assertConfig *As
 			}
 		}
 		if oldestSnapshot == nil {
-			msg := "No snapshots found"
-			return &msg
+			return Fail("No snapshots found")
 		} else {
 			diff := time.Since(*oldestSnapshot)
 			if diff < duration {
-				msg := fmt.Sprintf("Oldest snapshot is from %s ago", diff)
-				return &msg
+				return Fail(fmt.Sprintf("Oldest snapshot is from %s ago", diff)).WithValues(">= "+duration.String(), diff.Round(time.Second).String())
 			}
 		}
 	}
 
-	return nil
+	return Pass()
 }
 
 func NewBackupRetentionAssert() BackupRetentionAssert {
@@ -1,16 +1,36 @@
 package assert
 
+import "github.com/MaxxtonGroup/backup-validator/pkg/plugin"
+
 type AssertConfig struct {
+	Severity *string `yaml:"severity"`
+
 	FilesExists     *[]string                    `yaml:"filesExists"`
 	FileModified    *FileModifiedAssertConfig    `yaml:"fileModified"`
 	BackupRetention *BackupRetentionAssertConfig `yaml:"backupRetention"`
 	MaxRestoreTime  *string                      `yaml:"maxRestoreTime"`
 	MaxImportTime   *string                      `yaml:"maxImportTime"`
 
-	DatabasesExists *[]string                 `yaml:"databasesExists"`
-	DatabaseSize    *DatabaseSizeAssertConfig `yaml:"databaseSize"`
-	TablesExists    *TableExistsAssertConfig  `yaml:"tablesExists"`
-	QueryRecord     *QueryRecordAssertConfig  `yaml:"queryRecord"`
+	DatabasesExists *[]string                  `yaml:"databasesExists"`
+	DatabaseSize    *DatabaseSizeAssertConfig  `yaml:"databaseSize"`
+	TablesExists    *TableExistsAssertConfig   `yaml:"tablesExists"`
+	QueryRecord     *QueryRecordAssertConfig   `yaml:"queryRecord"`
+	RecoveryPoint   *RecoveryPointAssertConfig `yaml:"recoveryPoint"`
+	RowCount        *RowCountAssertConfig      `yaml:"rowCount"`
+	Growth          *GrowthAssertConfig        `yaml:"growth"`
+	SeriesExists    *SeriesExistsAssertConfig  `yaml:"seriesExists"`
+	IndexHealth     *IndexHealthAssertConfig   `yaml:"indexHealth"`
+	IndexMappings   *IndexMappingsAssertConfig `yaml:"indexMappings"`
+	MongoValidate   *MongoValidateAssertConfig `yaml:"mongoValidate"`
+	IndexesExist    *IndexesExistAssertConfig  `yaml:"indexesExist"`
+
+	PostgresIntegrity *PostgresIntegrityAssertConfig `yaml:"postgresIntegrity"`
+	SchemaMatches     *SchemaMatchesAssertConfig     `yaml:"schemaMatches"`
+
+	Exec   *ExecAssertConfig `yaml:"exec"`
+	Plugin *plugin.Config    `yaml:"plugin"`
+
+	RepositoryIntegrity *RepositoryIntegrityAssertConfig `yaml:"repositoryIntegrity"`
 }
 
 type FileModifiedAssertConfig struct {
@@ -29,6 +49,68 @@ type QueryRecordAssertConfig struct {
 	Matches  map[string]interface{}
 }
 
+type RecoveryPointAssertConfig struct {
+	MaxLag     string  `yaml:"maxLag"`
+	RelativeTo *string `yaml:"relativeTo"`
+	Database   *string `yaml:"database"`
+	Query      *string `yaml:"query"`
+	Field      *string `yaml:"field"`
+}
+
+type SeriesExistsAssertConfig struct {
+	Database   string  `yaml:"database"`
+	Series     string  `yaml:"series"`
+	Window     *string `yaml:"window"`
+	RelativeTo *string `yaml:"relativeTo"`
+	MinSamples *uint64 `yaml:"minSamples"`
+}
+
+type IndexHealthAssertConfig struct {
+	Index   string  `yaml:"index"`
+	Status  *string `yaml:"status"`
+	Timeout *string `yaml:"timeout"`
+}
+
+type IndexMappingsAssertConfig struct {
+	Index  string            `yaml:"index"`
+	Fields map[string]string `yaml:"fields"`
+}
+
+type MongoValidateAssertConfig struct {
+	Database    string    `yaml:"database"`
+	Collections *[]string `yaml:"collections"`
+	Full        bool      `yaml:"full"`
+}
+
+type IndexesExistAssertConfig struct {
+	Database string              `yaml:"database"`
+	Indexes  map[string][]string `yaml:"indexes"`
+}
+
+type PostgresIntegrityAssertConfig struct {
+	Database       string   `yaml:"database"`
+	Amcheck        *bool    `yaml:"amcheck"`
+	HeapAllIndexed bool     `yaml:"heapAllIndexed"`
+	Extensions     []string `yaml:"extensions"`
+	Roles          []string `yaml:"roles"`
+	Sequences      []string `yaml:"sequences"`
+	Vacuum         bool     `yaml:"vacuum"`
+}
+
+type SchemaMatchesAssertConfig struct {
+	File       string  `yaml:"file"`
+	Database   *string `yaml:"database"`
+	AllowExtra *bool   `yaml:"allowExtra"`
+}
+
+type ExecAssertConfig struct {
+	Command  []string               `yaml:"command"`
+	ExitCode *int                   `yaml:"exitCode"`
+	Stdout   *string                `yaml:"stdout"`
+	Json     map[string]interface{} `yaml:"json"`
+	Timeout  *string                `yaml:"timeout"`
+}
+
 type DatabaseSizeAssertConfig struct {
 	Database string `yaml:"database"`
 	Size     string `yaml:"size"`
@@ -38,3 +120,25 @@ type TableExistsAssertConfig struct {
 	Database string    `yaml:"database"`
 	Tables   *[]string `yaml:"tables"`
 }
+
+type RowCountAssertConfig struct {
+	Database string    `yaml:"database"`
+	Tables   *[]string `yaml:"tables"`
+	Min      *uint64   `yaml:"min"`
+	Max      *uint64   `yaml:"max"`
+	Equals   *uint64   `yaml:"equals"`
+	Estimate bool      `yaml:"estimate"`
+}
+
+type GrowthAssertConfig struct {
+	Metric    string  `yaml:"metric"`
+	MaxChange string  `yaml:"maxChange"`
+	CompareTo *string `yaml:"compareTo"`
+	Window    *int    `yaml:"window"`
+}
+
+type RepositoryIntegrityAssertConfig struct {
+	ReadDataSubset     *string `yaml:"readDataSubset"`
+	RotateSubsets      *int    `yaml:"rotateSubsets"`
+	IgnoreUnreferenced bool    `yaml:"ignoreUnreferenced"`
+}
@@ -5,55 +5,64 @@ import (
 
 	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
 	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/MaxxtonGroup/backup-validator/pkg/pattern"
 	"github.com/dustin/go-humanize"
 )
 
 type DatabasesSizeAssert struct {
 }
 
+func (a DatabasesSizeAssert) Type() string {
+	return "databaseSize"
+}
+
 func (a DatabasesSizeAssert) RunFor(assert *AssertConfig) bool {
 	return assert.DatabaseSize != nil
 }
 
-func (a DatabasesSizeAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot) *string {
+func (a DatabasesSizeAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
 	databases, err := formatProvider.ListDatabases(testName)
 	if err != nil {
-		msg := err.Error()
-		return &msg
+		return Error(err)
 	}
 
 	databaseName := assertConfig.DatabaseSize.Database
-	matchingDatabases, err := getMatchingDatabases(testName, databaseName, databases, formatProvider, *snapshot)
+	matchingDatabases, err := pattern.Match(databaseName, databases)
+	if err != nil {
+		return Error(err)
+	}
+
+	if len(matchingDatabases) == 0 {
+		return Fail(fmt.Sprintf("No databases found that match %s", databaseName))
+	}
+	minSize, err := humanize.ParseBytes(assertConfig.DatabaseSize.Size)
 	if err != nil {
-		msg := err.Error()
-		return &msg
+		return Error(err)
 	}
 
-	var msg string
+	// One of the databases should be large enough
+	details := []*Detail{}
 	for _, db := range matchingDatabases {
 		size, err := formatProvider.GetDatabaseSize(testName, db)
 		if err != nil {
-			msg = err.Error()
+			details = append(details, &Detail{Database: db, Status: StatusError, Message: err.Error()})
 			continue
 		}
+		history.Record("databaseSize:"+db, float64(*size))
 
-		maxSize, err := humanize.ParseBytes(assertConfig.DatabaseSize.Size)
-		if err != nil {
-			msg = err.Error()
-			continue
+		detail := &Detail{
+			Database: db,
+			Status:   StatusPass,
+			Expected: ">= " + assertConfig.DatabaseSize.Size,
+			Actual:   humanize.Bytes(*size),
 		}
-		if *size < maxSize {
-			currentSize := humanize.Bytes(*size)
-			msg = fmt.Sprintf("Database size %s is %s, but should be at least %s", db, currentSize, assertConfig.DatabaseSize.Size)
-		} else {
-			return nil
+		if *size < minSize {
+			detail.Status = StatusFail
+			detail.Message = fmt.Sprintf("size is %s, but should be at least %s", humanize.Bytes(*size), assertConfig.DatabaseSize.Size)
 		}
+		details = append(details, detail)
 	}
-
-	if msg != "" {
-		return &msg
-	}
-	return nil
+	return AnyOf(details, "Database too small")
 }
 
 func NewDatabasesSizeAssert() DatabasesSizeAssert {
@@ -1,39 +1,39 @@
 package assert
 
 import (
-	"fmt"
-	"regexp"
 	"strings"
-	"time"
 
 	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
 	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/MaxxtonGroup/backup-validator/pkg/pattern"
 )
 
 type DatabasesExistsAssert struct {
 }
 
+func (a DatabasesExistsAssert) Type() string {
+	return "databasesExists"
+}
+
 func (a DatabasesExistsAssert) RunFor(assert *AssertConfig) bool {
 	return assert.DatabasesExists != nil
 }
 
-func (a DatabasesExistsAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot) *string {
+func (a DatabasesExistsAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
 	var err error
 	databases := snapshot.Databases
 	if databases == nil {
 		databases, err = formatProvider.ListDatabases(testName)
 		if err != nil {
-			msg := err.Error()
-			return &msg
+			return Error(err)
 		}
 	}
 
 	missingDatabases := make([]string, 0)
 	for _, databaseName := range *assertConfig.DatabasesExists {
-		matchingDatabases, err := getMatchingDatabases(testName, databaseName, databases, formatProvider, *snapshot)
+		matchingDatabases, err := pattern.Match(databaseName, databases)
 		if err != nil {
-			msg := err.Error()
-			return &msg
+			return Error(err)
 		}
 
 		if len(matchingDatabases) == 0 {
@@ -42,38 +42,12 @@ func (a DatabasesExistsAssert) Run(testName string, dir string, assertConfig *As
 	}
 
 	if len(missingDatabases) > 0 {
-		msg := "Missing databases: " + strings.Join(missingDatabases, ", ")
-		return &msg
+		return Fail("Missing databases: " + strings.Join(missingDatabases, ", "))
 	}
-	return nil
+	return Pass()
 }
 
 func NewDatabasesExistsAssert() DatabasesExistsAssert {
 	databasesExistsAssert := DatabasesExistsAssert{}
 	return databasesExistsAssert
 }
-
-func getMatchingDatabases(testName string, databaseName string, databases []string, formatProvider format.FormatProvider, snapshot backup.Snapshot) ([]string, error) {
-	_, isElasticSearch := formatProvider.(format.ElasticsearchFormatProvider)
-	if isElasticSearch {
-		// Parse database name for elasticsearch
-		databaseName = snapshot.Time.Add(-(24 * time.Hour)).Format(databaseName)
-	}
-	parts := strings.Split(databaseName, "*")
-	for i, part := range parts {
-		parts[i] = regexp.QuoteMeta(part)
-	}
-	databaseRegexStr := strings.Join(parts, ".*")
-	databaseRegex, err := regexp.Compile(databaseRegexStr)
-	if err != nil {
-		return nil, fmt.Errorf("Invalid regex: " + databaseRegexStr)
-	}
-
-	matchingDatabases := []string{}
-	for _, database := range databases {
-		if databaseRegex.MatchString(database) {
-			matchingDatabases = append(matchingDatabases, database)
-		}
-	}
-	return matchingDatabases, nil
-}
@@ -0,0 +1,224 @@
+package assert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/MaxxtonGroup/backup-validator/pkg/runtime"
+)
+
+// defaultExecTimeout is the max time a command may run, the same as the timeout of plugins
+const defaultExecTimeout = time.Hour
+
+type ExecAssert struct {
+}
+
+func (a ExecAssert) Type() string {
+	return "exec"
+}
+
+func (a ExecAssert) RunFor(assert *AssertConfig) bool {
+	return assert.Exec != nil
+}
+
+func (a ExecAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
+	config := assertConfig.Exec
+	if len(config.Command) == 0 {
+		return Error(errors.New("exec: missing command"))
+	}
+
+	timeout := defaultExecTimeout
+	if config.Timeout != nil {
+		var err error
+		timeout, err = time.ParseDuration(*config.Timeout)
+		if err != nil {
+			return Error(fmt.Errorf("exec: invalid timeout '%s': %s", *config.Timeout, err))
+		}
+	}
+
+	stdout, stderr, exitCode, err := execCommand(testName, dir, config.Command, timeout, formatProvider, snapshot)
+	if err != nil {
+		return Error(err)
+	}
+	log.Printf("[%s] Command %s exited with %d", testName, config.Command[0], exitCode)
+
+	expectedExitCode := 0
+	if config.ExitCode != nil {
+		expectedExitCode = *config.ExitCode
+	}
+	if exitCode != expectedExitCode {
+		return Fail(fmt.Sprintf("Command %s exited with %d, expected %d: %s", config.Command[0], exitCode, expectedExitCode, commandOutput(stdout, stderr))).WithValues(fmt.Sprintf("exit code %d", expectedExitCode), fmt.Sprintf("exit code %d", exitCode))
+	}
+
+	if config.Stdout != nil {
+		regex, err := regexp.Compile(*config.Stdout)
+		if err != nil {
+			return Error(fmt.Errorf("Invalid regex %s: %s", *config.Stdout, err))
+		}
+		if !regex.MatchString(stdout) {
+			return Fail(fmt.Sprintf("Output of %s doesn't match %s: %s", config.Command[0], *config.Stdout, commandOutput(stdout, stderr)))
+		}
+	}
+
+	if len(config.Json) > 0 {
+		var output interface{}
+		err = json.Unmarshal([]byte(stdout), &output)
+		if err != nil {
+			return Fail(fmt.Sprintf("Output of %s isn't valid json: %s", config.Command[0], err))
+		}
+
+		fields := []string{}
+		for field := range config.Json {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		problems := []string{}
+		for _, field := range fields {
+			value, ok := lookupJsonField(output, field)
+			if !ok {
+				problems = append(problems, fmt.Sprintf("%s is missing", field))
+			} else if !reflect.DeepEqual(value, config.Json[field]) {
+				problems = append(problems, fmt.Sprintf("%s is %v instead of %v", field, value, config.Json[field]))
+			}
+		}
+		if len(problems) > 0 {
+			return Fail(fmt.Sprintf("Output of %s doesn't match: %s", config.Command[0], strings.Join(problems, ", ")))
+		}
+	}
+	return Pass()
+}
+
+// execCommand runs the command in the runtime of the format, or on the host in the workdir when the format has no runtime.
+// The command is killed when it runs longer than the timeout.
+func execCommand(testName string, dir string, command []string, timeout time.Duration, formatProvider format.FormatProvider, snapshot *backup.Snapshot) (string, string, int, error) {
+	var runtimeProvider runtime.RuntimeProvider
+	if runtimeFormatProvider, ok := formatProvider.(format.RuntimeFormatProvider); ok {
+		runtimeProvider = runtimeFormatProvider.GetRuntimeProvider()
+	}
+
+	workDir := "/mnt/host/workdir"
+	if runtimeProvider == nil {
+		absDir, err := filepath.Abs(filepath.Join(dir, "workdir"))
+		if err != nil {
+			return "", "", 0, err
+		}
+		workDir = absDir
+	}
+	env := []string{
+		"BACKUP_VALIDATOR_TEST_NAME=" + testName,
+		"BACKUP_VALIDATOR_SNAPSHOT_TIME=" + snapshot.Time.Format(time.RFC3339),
+		"BACKUP_VALIDATOR_WORKDIR=" + workDir,
+	}
+
+	if runtimeProvider != nil {
+		return execRuntimeCommand(testName, runtimeProvider, env, command, timeout)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(), env...)
+	var stderr bytes.Buffer
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+	output, err := cmd.Output()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", "", 0, fmt.Errorf("exec: %s didn't finish within %s, the command is killed", command[0], timeout)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return string(output), stderr.String(), exitErr.ExitCode(), nil
+	} else if err != nil {
+		return "", "", 0, err
+	}
+	return string(output), stderr.String(), 0, nil
+}
+
+// execRuntimeCommand runs the command in the container, the process id is kept in a file so the command can be killed after the timeout
+func execRuntimeCommand(testName string, runtimeProvider runtime.RuntimeProvider, env []string, command []string, timeout time.Duration) (string, string, int, error) {
+	pidFile := fmt.Sprintf("/tmp/backup-validator-exec-%d.pid", time.Now().UnixNano())
+	args := append([]string{"-c", "echo $$ > " + pidFile + " && exec env \"$@\"", "sh"}, append(env, command...)...)
+
+	type execution struct {
+		output *string
+		err    error
+	}
+	done := make(chan execution, 1)
+	go func() {
+		output, err := runtimeProvider.Exec(testName, "sh", args...)
+		done <- execution{output: output, err: err}
+	}()
+
+	var result execution
+	select {
+	case result = <-done:
+	case <-time.After(timeout):
+		_, err := runtimeProvider.ExecRoot(testName, "sh", "-c", "kill -9 $(cat "+pidFile+")")
+		if err != nil {
+			log.Printf("[%s] Failed to kill %s: %s", testName, command[0], err)
+		}
+		return "", "", 0, fmt.Errorf("exec: %s didn't finish within %s, the command is killed", command[0], timeout)
+	}
+	runtimeProvider.Exec(testName, "rm", "-f", pidFile)
+
+	var execErr *runtime.ExecError
+	if errors.As(result.err, &execErr) && execErr.ExitCode >= 0 {
+		return execErr.Stdout, execErr.Stderr, execErr.ExitCode, nil
+	} else if result.err != nil {
+		return "", "", 0, result.err
+	}
+	return *result.output, "", 0, nil
+}
+
+// commandOutput combines the output of a command for a failure message
+func commandOutput(stdout string, stderr string) string {
+	output := strings.TrimSpace(stdout)
+	if errOutput := strings.TrimSpace(stderr); errOutput != "" {
+		output = strings.TrimSpace(output + "\nstderr: " + errOutput)
+	}
+	return output
+}
+
+// lookupJsonField returns the value at the path in the json value, eg. checks.failed or items.0.name
+func lookupJsonField(value interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			var ok bool
+			value, ok = typed[key]
+			if !ok {
+				return nil, false
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(typed) {
+				return nil, false
+			}
+			value = typed[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func NewExecAssert() ExecAssert {
+	execAssert := ExecAssert{}
+	return execAssert
+}
@@ -13,30 +13,31 @@ import (
 type FileModifiedAssert struct {
 }
 
+func (a FileModifiedAssert) Type() string {
+	return "fileModified"
+}
+
 func (a FileModifiedAssert) RunFor(assert *AssertConfig) bool {
 	return assert.FileModified != nil
 }
 
-func (a FileModifiedAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot) *string {
+func (a FileModifiedAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
 	pattern := filepath.Join(dir, "workdir", assertConfig.FileModified.File)
 
 	// Find matching files
 	matchingFiles, err := filepath.Glob(pattern)
 	if err != nil {
-		errMsg := fmt.Sprintf("Invalid glob pattern: %s", pattern)
-		return &errMsg
+		return Error(fmt.Errorf("Invalid glob pattern: %s", pattern))
 	}
 
 	if len(matchingFiles) == 0 {
-		errMsg := fmt.Sprintf("No matching files for %s", pattern)
-		return &errMsg
+		return Fail(fmt.Sprintf("No matching files for %s", pattern))
 	}
 
 	// get max duration
 	duration, err := time.ParseDuration(assertConfig.FileModified.NewerThan)
 	if err != nil {
-		errMsg := err.Error()
-		return &errMsg
+		return Error(err)
 	}
 
 	// Find file within the max duration
@@ -45,13 +46,12 @@ func (a FileModifiedAssert) Run(testName string, dir string, assertConfig *Asser
 	for _, file := range matchingFiles {
 		stats, err := os.Stat(file)
 		if err != nil {
-			errMsg := err.Error()
-			return &errMsg
+			return Error(err)
 		}
 
 		mTime := time.Since(stats.ModTime())
 		if mTime <= duration {
-			return nil
+			return Pass()
 		}
 		if newestMTime == nil || mTime < *newestMTime {
 			newestFile = file
@@ -59,8 +59,7 @@ func (a FileModifiedAssert) Run(testName string, dir string, assertConfig *Asser
 		}
 	}
 
-	msg := fmt.Sprintf("%s is modified %s ago", newestFile, newestMTime)
-	return &msg
+	return Fail(fmt.Sprintf("%s is modified %s ago", newestFile, newestMTime))
 }
 
 func NewFileModifiedAssert() FileModifiedAssert {
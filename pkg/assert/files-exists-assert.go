@@ -1,6 +1,7 @@
 package assert
 
 import (
+	"errors"
 	"path/filepath"
 	"strings"
 
@@ -11,11 +12,15 @@ import (
 type FilesExistsAssert struct {
 }
 
+func (a FilesExistsAssert) Type() string {
+	return "filesExists"
+}
+
 func (a FilesExistsAssert) RunFor(assert *AssertConfig) bool {
 	return assert.FilesExists != nil
 }
 
-func (a FilesExistsAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot) *string {
+func (a FilesExistsAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
 	missingFiles := make([]string, 0)
 	invalidGlobPatterns := make([]string, 0)
 
@@ -33,14 +38,12 @@ func (a FilesExistsAssert) Run(testName string, dir string, assertConfig *Assert
 	}
 
 	if len(invalidGlobPatterns) > 0 {
-		msg := "Invalid Glob patterns: " + strings.Join(invalidGlobPatterns, ", ")
-		return &msg
+		return Error(errors.New("Invalid Glob patterns: " + strings.Join(invalidGlobPatterns, ", ")))
 	}
 	if len(missingFiles) > 0 {
-		msg := "Missing files: " + strings.Join(missingFiles, ", ")
-		return &msg
+		return Fail("Missing files: " + strings.Join(missingFiles, ", "))
 	}
-	return nil
+	return Pass()
 }
 
 func NewFilesExistsAssert() FilesExistsAssert {
@@ -0,0 +1,129 @@
+package assert
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/MaxxtonGroup/backup-validator/pkg/pattern"
+)
+
+type GrowthAssert struct {
+}
+
+func (a GrowthAssert) Type() string {
+	return "growth"
+}
+
+func (a GrowthAssert) RunFor(assert *AssertConfig) bool {
+	return assert.Growth != nil
+}
+
+func (a GrowthAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
+	config := assertConfig.Growth
+	maxChange, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(config.MaxChange), "%"), 64)
+	if err != nil {
+		return Error(fmt.Errorf("Invalid maxChange '%s': %s", config.MaxChange, err))
+	}
+	compareTo := "previous"
+	if config.CompareTo != nil {
+		compareTo = *config.CompareTo
+	}
+	if compareTo != "previous" && compareTo != "median" {
+		return Error(fmt.Errorf("Invalid compareTo '%s', should be one of: \"previous\" or \"median\"", compareTo))
+	}
+	window := 7
+	if config.Window != nil {
+		window = *config.Window
+	}
+	reference := "the previous run"
+	if compareTo == "median" {
+		reference = fmt.Sprintf("the median of the last %d runs", window)
+	}
+
+	// Find the metrics measured in this run or in the previous run, so a database that disappeared is noticed as well
+	names := []string{}
+	if history != nil {
+		for name := range history.Current {
+			names = append(names, name)
+		}
+		if len(history.Previous) > 0 {
+			for name := range history.Previous[len(history.Previous)-1].Metrics {
+				if _, ok := history.Current[name]; !ok {
+					names = append(names, name)
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	matchingNames, err := pattern.Match(config.Metric, names)
+	if err != nil {
+		return Error(err)
+	}
+	if len(matchingNames) == 0 {
+		return Fail(fmt.Sprintf("No measurements found for %s", config.Metric))
+	}
+
+	details := []*Detail{}
+	for _, name := range matchingNames {
+		current, measured := history.Current[name]
+		var baseline *float64
+		if compareTo == "median" {
+			baseline = history.MedianValue(name, window)
+		} else {
+			baseline = history.PreviousValue(name)
+		}
+		if baseline == nil {
+			log.Printf("[%s] No previous measurement of %s, skipping growth check", testName, name)
+			details = append(details, &Detail{Database: name, Status: StatusSkip, Message: "no previous measurement"})
+			continue
+		}
+		if !measured {
+			details = append(details, &Detail{
+				Database: name,
+				Status:   StatusFail,
+				Message:  fmt.Sprintf("isn't measured anymore (was %s)", formatMetric(*baseline)),
+				Expected: formatMetric(*baseline) + " ± " + config.MaxChange,
+				Actual:   "missing",
+			})
+			continue
+		}
+		if *baseline == 0 {
+			details = append(details, &Detail{Database: name, Status: StatusSkip, Message: "previous measurement is 0"})
+			continue
+		}
+
+		change := (current - *baseline) / *baseline * 100
+		detail := &Detail{
+			Database: name,
+			Status:   StatusPass,
+			Expected: formatMetric(*baseline) + " ± " + config.MaxChange,
+			Actual:   formatMetric(current),
+		}
+		if math.Abs(change) > maxChange {
+			detail.Status = StatusFail
+			detail.Message = fmt.Sprintf("changed %+.1f%% (from %s to %s)", change, formatMetric(*baseline), formatMetric(current))
+		}
+		details = append(details, detail)
+	}
+
+	result := ResultOf(details, fmt.Sprintf("Changed more than %s compared to %s", config.MaxChange, reference))
+	if result.Status == StatusSkip {
+		result.Message = "No previous measurements to compare with"
+	}
+	return result
+}
+
+func NewGrowthAssert() GrowthAssert {
+	growthAssert := GrowthAssert{}
+	return growthAssert
+}
+
+func formatMetric(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
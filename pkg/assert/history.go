@@ -0,0 +1,64 @@
+package assert
+
+import (
+	"sort"
+	"time"
+)
+
+// Metrics holds measurements of a validation run, eg. "databaseSize:shop" or "rowCount:shop.orders"
+type Metrics map[string]float64
+
+type MetricsRecord struct {
+	Time     time.Time `json:"time"`
+	Snapshot string    `json:"snapshot"`
+	Metrics  Metrics   `json:"metrics"`
+}
+
+// History holds the metrics of the current run and the metrics of previous runs of the same test
+type History struct {
+	Current  Metrics
+	Previous []*MetricsRecord
+}
+
+// Record stores a measurement of the current run
+func (h *History) Record(name string, value float64) {
+	if h != nil && h.Current != nil {
+		h.Current[name] = value
+	}
+}
+
+// PreviousValue returns the value of a metric in the last run it was measured
+func (h *History) PreviousValue(name string) *float64 {
+	if h == nil {
+		return nil
+	}
+	for i := len(h.Previous) - 1; i >= 0; i-- {
+		if value, ok := h.Previous[i].Metrics[name]; ok {
+			return &value
+		}
+	}
+	return nil
+}
+
+// MedianValue returns the median value of a metric over the last runs it was measured
+func (h *History) MedianValue(name string, window int) *float64 {
+	if h == nil {
+		return nil
+	}
+	values := []float64{}
+	for i := len(h.Previous) - 1; i >= 0 && len(values) < window; i-- {
+		if value, ok := h.Previous[i].Metrics[name]; ok {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	sort.Float64s(values)
+	median := values[len(values)/2]
+	if len(values)%2 == 0 {
+		median = (values[len(values)/2-1] + values[len(values)/2]) / 2
+	}
+	return &median
+}
@@ -0,0 +1,105 @@
+package assert
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/MaxxtonGroup/backup-validator/pkg/pattern"
+)
+
+var indexHealthLevels = map[string]int{
+	"red":    0,
+	"yellow": 1,
+	"green":  2,
+}
+
+type IndexHealthAssert struct {
+}
+
+func (a IndexHealthAssert) Type() string {
+	return "indexHealth"
+}
+
+func (a IndexHealthAssert) RunFor(assert *AssertConfig) bool {
+	return assert.IndexHealth != nil
+}
+
+func (a IndexHealthAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
+	config := assertConfig.IndexHealth
+	elasticsearchFormatProvider, ok := formatProvider.(format.ElasticsearchFormatProvider)
+	if !ok {
+		return Error(errors.New("indexHealth: only available for the elasticsearch format"))
+	}
+
+	// Replicas aren't allocated on a single node, so restored indices with replicas are at most yellow
+	status := "yellow"
+	if config.Status != nil {
+		status = *config.Status
+	}
+	minLevel, ok := indexHealthLevels[status]
+	if !ok {
+		return Error(fmt.Errorf("indexHealth: unsupported status '%s', should be one of: green, yellow, red", status))
+	}
+	timeout := time.Duration(0)
+	if config.Timeout != nil {
+		var err error
+		timeout, err = time.ParseDuration(*config.Timeout)
+		if err != nil {
+			return Error(err)
+		}
+	}
+	indexPattern := "*"
+	if config.Index != "" {
+		indexPattern = config.Index
+	}
+
+	// Shards may still be allocated after the recovery, so wait until the indices reach the status
+	deadline := time.Now().Add(timeout)
+	for {
+		health, err := elasticsearchFormatProvider.GetIndexHealth(testName)
+		if err != nil {
+			return Error(err)
+		}
+		indices := []string{}
+		for index := range health {
+			indices = append(indices, index)
+		}
+		sort.Strings(indices)
+		matchingIndices, err := pattern.Match(indexPattern, indices)
+		if err != nil {
+			return Error(err)
+		}
+		if len(matchingIndices) == 0 {
+			return Fail(fmt.Sprintf("No indices found that match %s", indexPattern))
+		}
+
+		details := []*Detail{}
+		for _, index := range matchingIndices {
+			detail := &Detail{Database: index, Status: StatusPass, Expected: status, Actual: health[index]}
+			if indexHealthLevels[health[index]] < minLevel {
+				detail.Status = StatusFail
+				detail.Message = health[index]
+			}
+			details = append(details, detail)
+		}
+		result := ResultOf(details, fmt.Sprintf("Indices aren't %s", status))
+		if result.Status == StatusPass {
+			log.Printf("[%s] %d indices are %s or better", testName, len(matchingIndices), status)
+			return result
+		}
+		if time.Now().After(deadline) {
+			return result
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
+func NewIndexHealthAssert() IndexHealthAssert {
+	indexHealthAssert := IndexHealthAssert{}
+	return indexHealthAssert
+}
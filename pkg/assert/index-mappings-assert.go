@@ -0,0 +1,81 @@
+package assert
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/MaxxtonGroup/backup-validator/pkg/pattern"
+)
+
+type IndexMappingsAssert struct {
+}
+
+func (a IndexMappingsAssert) Type() string {
+	return "indexMappings"
+}
+
+func (a IndexMappingsAssert) RunFor(assert *AssertConfig) bool {
+	return assert.IndexMappings != nil
+}
+
+func (a IndexMappingsAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
+	config := assertConfig.IndexMappings
+	elasticsearchFormatProvider, ok := formatProvider.(format.ElasticsearchFormatProvider)
+	if !ok {
+		return Error(errors.New("indexMappings: only available for the elasticsearch format"))
+	}
+
+	indices, err := formatProvider.ListDatabases(testName)
+	if err != nil {
+		return Error(err)
+	}
+	matchingIndices, err := pattern.Match(config.Index, indices)
+	if err != nil {
+		return Error(err)
+	}
+	if len(matchingIndices) == 0 {
+		return Fail(fmt.Sprintf("No indices found that match %s", config.Index))
+	}
+
+	fieldNames := []string{}
+	for field := range config.Fields {
+		fieldNames = append(fieldNames, field)
+	}
+	sort.Strings(fieldNames)
+
+	// Every matching index should have the fields with the expected type
+	details := []*Detail{}
+	for _, index := range matchingIndices {
+		mappings, err := elasticsearchFormatProvider.GetMappings(testName, index)
+		if err != nil {
+			details = append(details, &Detail{Database: index, Status: StatusError, Message: err.Error()})
+			continue
+		}
+		fields := mappings[index]
+		problems := []string{}
+		for _, field := range fieldNames {
+			expectedType := config.Fields[field]
+			fieldType, ok := fields[field]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("missing field %s", field))
+			} else if expectedType != "" && expectedType != fieldType {
+				problems = append(problems, fmt.Sprintf("field %s has type %s instead of %s", field, fieldType, expectedType))
+			}
+		}
+		if len(problems) > 0 {
+			details = append(details, &Detail{Database: index, Status: StatusFail, Message: strings.Join(problems, "; ")})
+		} else {
+			details = append(details, &Detail{Database: index, Status: StatusPass})
+		}
+	}
+	return ResultOf(details, "Invalid mappings")
+}
+
+func NewIndexMappingsAssert() IndexMappingsAssert {
+	indexMappingsAssert := IndexMappingsAssert{}
+	return indexMappingsAssert
+}
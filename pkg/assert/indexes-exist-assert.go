@@ -0,0 +1,82 @@
+package assert
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/MaxxtonGroup/backup-validator/pkg/pattern"
+)
+
+type IndexesExistAssert struct {
+}
+
+func (a IndexesExistAssert) Type() string {
+	return "indexesExist"
+}
+
+func (a IndexesExistAssert) RunFor(assert *AssertConfig) bool {
+	return assert.IndexesExist != nil
+}
+
+func (a IndexesExistAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
+	config := assertConfig.IndexesExist
+	mongoFormatProvider, ok := formatProvider.(format.MongoFormatProvider)
+	if !ok {
+		return Error(errors.New("indexesExist: only available for the mongo format"))
+	}
+
+	databases, err := formatProvider.ListDatabases(testName)
+	if err != nil {
+		return Error(err)
+	}
+	matchingDatabases, err := pattern.Match(config.Database, databases)
+	if err != nil {
+		return Error(err)
+	}
+	if len(matchingDatabases) == 0 {
+		return Fail(fmt.Sprintf("No databases found that match %s", config.Database))
+	}
+
+	collections := []string{}
+	for collection := range config.Indexes {
+		collections = append(collections, collection)
+	}
+	sort.Strings(collections)
+
+	details := []*Detail{}
+	for _, database := range matchingDatabases {
+		for _, collection := range collections {
+			name := database + "." + collection
+			indexes, err := mongoFormatProvider.ListIndexes(testName, database, collection)
+			if err != nil {
+				details = append(details, &Detail{Database: name, Status: StatusError, Message: err.Error()})
+				continue
+			}
+			indexNames := map[string]bool{}
+			for _, index := range indexes {
+				indexNames[index.Name] = true
+			}
+			missingIndexes := []string{}
+			for _, indexName := range config.Indexes[collection] {
+				if !indexNames[indexName] {
+					missingIndexes = append(missingIndexes, indexName)
+				}
+			}
+			if len(missingIndexes) > 0 {
+				details = append(details, &Detail{Database: name, Status: StatusFail, Message: strings.Join(missingIndexes, ", ")})
+			} else {
+				details = append(details, &Detail{Database: name, Status: StatusPass})
+			}
+		}
+	}
+	return ResultOf(details, "Missing indexes")
+}
+
+func NewIndexesExistAssert() IndexesExistAssert {
+	indexesExistAssert := IndexesExistAssert{}
+	return indexesExistAssert
+}
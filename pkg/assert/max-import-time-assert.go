@@ -11,22 +11,24 @@ import (
 type MaxImportTimeAssert struct {
 }
 
+func (a MaxImportTimeAssert) Type() string {
+	return "maxImportTime"
+}
+
 func (a MaxImportTimeAssert) RunFor(assert *AssertConfig) bool {
 	return assert.MaxImportTime != nil
 }
 
-func (a MaxImportTimeAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot) *string {
+func (a MaxImportTimeAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
 	maxImportTime, err := time.ParseDuration(*assertConfig.MaxImportTime)
 	if err != nil {
-		errMsg := err.Error()
-		return &errMsg
+		return Error(err)
 	}
 
 	if timings.ImportTime > maxImportTime {
-		errMsg := fmt.Sprintf("Importing database took %s, which is more than %s", timings.ImportTime.Round(time.Second), maxImportTime.Round(time.Second))
-		return &errMsg
+		return Fail(fmt.Sprintf("Importing database took %s, which is more than %s", timings.ImportTime.Round(time.Second), maxImportTime.Round(time.Second))).WithValues("<= "+maxImportTime.String(), timings.ImportTime.Round(time.Second).String())
 	}
-	return nil
+	return Pass().WithValues("<= "+maxImportTime.String(), timings.ImportTime.Round(time.Second).String())
 }
 
 func NewMaxImportTimeAssert() MaxImportTimeAssert {
@@ -11,22 +11,24 @@ import (
 type MaxRestoreTimeAssert struct {
 }
 
+func (a MaxRestoreTimeAssert) Type() string {
+	return "maxRestoreTime"
+}
+
 func (a MaxRestoreTimeAssert) RunFor(assert *AssertConfig) bool {
 	return assert.MaxRestoreTime != nil
 }
 
-func (a MaxRestoreTimeAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot) *string {
+func (a MaxRestoreTimeAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
 	maxRestoreTime, err := time.ParseDuration(*assertConfig.MaxRestoreTime)
 	if err != nil {
-		errMsg := err.Error()
-		return &errMsg
+		return Error(err)
 	}
 
 	if timings.RestoreTime > maxRestoreTime {
-		errMsg := fmt.Sprintf("Restore took %s, which is more than %s", timings.RestoreTime.Round(time.Second), maxRestoreTime.Round(time.Second))
-		return &errMsg
+		return Fail(fmt.Sprintf("Restore took %s, which is more than %s", timings.RestoreTime.Round(time.Second), maxRestoreTime.Round(time.Second))).WithValues("<= "+maxRestoreTime.String(), timings.RestoreTime.Round(time.Second).String())
 	}
-	return nil
+	return Pass().WithValues("<= "+maxRestoreTime.String(), timings.RestoreTime.Round(time.Second).String())
 }
 
 func NewMaxRestoreTimeAssert() MaxRestoreTimeAssert {
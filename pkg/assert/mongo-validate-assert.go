@@ -0,0 +1,100 @@
+package assert
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/MaxxtonGroup/backup-validator/pkg/pattern"
+)
+
+type MongoValidateAssert struct {
+}
+
+func (a MongoValidateAssert) Type() string {
+	return "mongoValidate"
+}
+
+func (a MongoValidateAssert) RunFor(assert *AssertConfig) bool {
+	return assert.MongoValidate != nil
+}
+
+func (a MongoValidateAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
+	config := assertConfig.MongoValidate
+	mongoFormatProvider, ok := formatProvider.(format.MongoFormatProvider)
+	if !ok {
+		return Error(errors.New("mongoValidate: only available for the mongo format"))
+	}
+
+	databases, err := formatProvider.ListDatabases(testName)
+	if err != nil {
+		return Error(err)
+	}
+	matchingDatabases, err := pattern.Match(config.Database, databases)
+	if err != nil {
+		return Error(err)
+	}
+	if len(matchingDatabases) == 0 {
+		return Fail(fmt.Sprintf("No databases found that match %s", config.Database))
+	}
+
+	details := []*Detail{}
+	validated := 0
+	for _, database := range matchingDatabases {
+		collections, err := formatProvider.ListTables(testName, database)
+		if err != nil {
+			return Error(err)
+		}
+		if config.Collections != nil {
+			matchingCollections := []string{}
+			for _, collectionPattern := range *config.Collections {
+				matches, err := pattern.Match(collectionPattern, collections)
+				if err != nil {
+					return Error(err)
+				}
+				if len(matches) == 0 {
+					details = append(details, &Detail{Database: database, Status: StatusFail, Message: "no collections matching " + collectionPattern})
+				}
+				matchingCollections = append(matchingCollections, matches...)
+			}
+			collections = matchingCollections
+		} else if len(collections) == 0 {
+			// An empty or truncated restore has nothing to validate
+			details = append(details, &Detail{Database: database, Status: StatusFail, Message: "no collections"})
+		}
+
+		for _, collection := range collections {
+			name := database + "." + collection
+			result, err := mongoFormatProvider.ValidateCollection(testName, database, collection, config.Full)
+			if err != nil {
+				details = append(details, &Detail{Database: name, Status: StatusError, Message: err.Error()})
+				continue
+			}
+			validated++
+			for _, warning := range result.Warnings {
+				log.Printf("[%s] %s: %s", testName, name, warning)
+			}
+			if !result.Valid {
+				message := "corrupt"
+				if len(result.Errors) > 0 {
+					message += " (" + strings.Join(result.Errors, "; ") + ")"
+				}
+				details = append(details, &Detail{Database: name, Status: StatusFail, Message: message})
+			} else if len(result.Warnings) > 0 {
+				details = append(details, &Detail{Database: name, Status: StatusWarn, Message: strings.Join(result.Warnings, "; ")})
+			} else {
+				details = append(details, &Detail{Database: name, Status: StatusPass})
+			}
+		}
+	}
+	log.Printf("[%s] Validated %d collections", testName, validated)
+	return ResultOf(details, "Invalid collections")
+}
+
+func NewMongoValidateAssert() MongoValidateAssert {
+	mongoValidateAssert := MongoValidateAssert{}
+	return mongoValidateAssert
+}
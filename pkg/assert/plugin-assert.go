@@ -0,0 +1,82 @@
+package assert
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/MaxxtonGroup/backup-validator/pkg/plugin"
+)
+
+type PluginAssert struct {
+}
+
+func (a PluginAssert) Type() string {
+	return "plugin"
+}
+
+func (a PluginAssert) RunFor(assert *AssertConfig) bool {
+	return assert.Plugin != nil
+}
+
+func (a PluginAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
+	assertPlugin, err := plugin.Start("assert", testName, *assertConfig.Plugin)
+	if err != nil {
+		return Error(err)
+	}
+	defer assertPlugin.Close()
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return Error(err)
+	}
+	// Not every format has databases
+	databases, _ := formatProvider.ListDatabases(testName)
+
+	result := plugin.RunResult{}
+	err = assertPlugin.Call("run", plugin.RunParams{
+		Dir:            absDir,
+		Snapshot:       plugin.NewSnapshotMessage(snapshot),
+		RestoreSeconds: timings.RestoreTime.Seconds(),
+		ImportSeconds:  timings.ImportTime.Seconds(),
+		Databases:      databases,
+	}, &result)
+	if err != nil {
+		return Error(err)
+	}
+	return newPluginResult(result)
+}
+
+func newPluginResult(runResult plugin.RunResult) *Result {
+	result := Pass()
+	if runResult.Message != nil {
+		result = Fail(*runResult.Message)
+	}
+	if runResult.Status != nil {
+		result.Status = Status(*runResult.Status)
+		if _, ok := statusOrder[result.Status]; !ok {
+			return Error(fmt.Errorf("plugin: unsupported status '%s', should be one of: pass, fail, warn, skip or error", *runResult.Status))
+		}
+	}
+	result.Expected = runResult.Expected
+	result.Actual = runResult.Actual
+	for _, detail := range runResult.Details {
+		if _, ok := statusOrder[Status(detail.Status)]; !ok {
+			return Error(fmt.Errorf("plugin: unsupported status '%s' of %s, should be one of: pass, fail, warn, skip or error", detail.Status, detail.Database))
+		}
+		result.Details = append(result.Details, &Detail{
+			Database: detail.Database,
+			Status:   Status(detail.Status),
+			Message:  detail.Message,
+			Expected: detail.Expected,
+			Actual:   detail.Actual,
+		})
+	}
+	return result
+}
+
+func NewPluginAssert() PluginAssert {
+	pluginAssert := PluginAssert{}
+	return pluginAssert
+}
@@ -0,0 +1,74 @@
+package assert
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/MaxxtonGroup/backup-validator/pkg/pattern"
+)
+
+type PostgresIntegrityAssert struct {
+}
+
+func (a PostgresIntegrityAssert) Type() string {
+	return "postgresIntegrity"
+}
+
+func (a PostgresIntegrityAssert) RunFor(assert *AssertConfig) bool {
+	return assert.PostgresIntegrity != nil
+}
+
+func (a PostgresIntegrityAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
+	config := assertConfig.PostgresIntegrity
+	postgresqlFormatProvider, ok := formatProvider.(format.PostgresqlFormatProvider)
+	if !ok {
+		return Error(errors.New("postgresIntegrity: only available for the postgresql format"))
+	}
+
+	databasePattern := "*"
+	if config.Database != "" {
+		databasePattern = config.Database
+	}
+	databases, err := formatProvider.ListDatabases(testName)
+	if err != nil {
+		return Error(err)
+	}
+	matchingDatabases, err := pattern.Match(databasePattern, databases)
+	if err != nil {
+		return Error(err)
+	}
+	if len(matchingDatabases) == 0 {
+		return Fail(fmt.Sprintf("No databases found that match %s", databasePattern))
+	}
+
+	check := format.PostgresqlIntegrityCheck{
+		Amcheck:        config.Amcheck == nil || *config.Amcheck,
+		HeapAllIndexed: config.HeapAllIndexed,
+		Extensions:     config.Extensions,
+		Roles:          config.Roles,
+		Sequences:      config.Sequences,
+		Vacuum:         config.Vacuum,
+	}
+	details := []*Detail{}
+	for _, database := range matchingDatabases {
+		log.Printf("[%s] Checking integrity of %s", testName, database)
+		databaseProblems, err := postgresqlFormatProvider.CheckIntegrity(testName, database, check)
+		if err != nil {
+			details = append(details, &Detail{Database: database, Status: StatusError, Message: err.Error()})
+		} else if len(databaseProblems) > 0 {
+			details = append(details, &Detail{Database: database, Status: StatusFail, Message: strings.Join(databaseProblems, "; ")})
+		} else {
+			details = append(details, &Detail{Database: database, Status: StatusPass})
+		}
+	}
+	return ResultOf(details, "Integrity problems")
+}
+
+func NewPostgresIntegrityAssert() PostgresIntegrityAssert {
+	postgresIntegrityAssert := PostgresIntegrityAssert{}
+	return postgresIntegrityAssert
+}
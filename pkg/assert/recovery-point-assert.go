@@ -0,0 +1,127 @@
+package assert
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+)
+
+var recoveryTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+}
+
+type RecoveryPointAssert struct {
+}
+
+func (a RecoveryPointAssert) Type() string {
+	return "recoveryPoint"
+}
+
+func (a RecoveryPointAssert) RunFor(assert *AssertConfig) bool {
+	return assert.RecoveryPoint != nil
+}
+
+func (a RecoveryPointAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
+	config := assertConfig.RecoveryPoint
+	maxLag, err := time.ParseDuration(config.MaxLag)
+	if err != nil {
+		return Error(err)
+	}
+
+	// Find the recovery point with a query or from the recovery metadata of the format
+	var recoveryTime *time.Time
+	if config.Query != nil {
+		if config.Database == nil {
+			return Error(errors.New("recoveryPoint: 'database' is required when using a 'query'"))
+		}
+		record, err := formatProvider.QueryRecord(testName, *config.Database, *config.Query)
+		if err != nil {
+			return Error(err)
+		}
+		recoveryTime, err = getRecoveryTime(record, config.Field)
+		if err != nil {
+			return Error(err)
+		}
+	} else {
+		recoveryPointProvider, ok := formatProvider.(format.RecoveryPointProvider)
+		if !ok {
+			return Error(errors.New("recoveryPoint: format has no recovery metadata, use a 'query' instead"))
+		}
+		recoveryPoint, err := recoveryPointProvider.GetRecoveryPoint(testName)
+		if err != nil {
+			return Error(err)
+		}
+		if recoveryPoint != nil {
+			recoveryTime = recoveryPoint.Time
+		}
+	}
+	if recoveryTime == nil {
+		return Fail("No recovery point found")
+	}
+
+	// Compare with the snapshot time or the current time
+	referenceName := "snapshot"
+	referenceTime := snapshot.Time
+	if config.RelativeTo != nil && *config.RelativeTo == "now" {
+		referenceName = "current"
+		referenceTime = time.Now()
+	}
+	lag := referenceTime.Sub(*recoveryTime)
+	log.Printf("[%s] Recovery point is %s, data lag is %s", testName, recoveryTime.Format(time.RFC3339), lag.Round(time.Second))
+
+	if lag > maxLag {
+		return Fail(fmt.Sprintf("Data is recovered until %s, which is %s before the %s time and more than %s", recoveryTime.Format(time.RFC3339), lag.Round(time.Second), referenceName, maxLag)).WithValues("<= "+maxLag.String(), lag.Round(time.Second).String())
+	}
+	return Pass().WithValues("<= "+maxLag.String(), lag.Round(time.Second).String())
+}
+
+func NewRecoveryPointAssert() RecoveryPointAssert {
+	recoveryPointAssert := RecoveryPointAssert{}
+	return recoveryPointAssert
+}
+
+func getRecoveryTime(record map[string]interface{}, field *string) (*time.Time, error) {
+	var value interface{}
+	if field != nil {
+		value = record[*field]
+	} else if len(record) == 1 {
+		for _, v := range record {
+			value = v
+		}
+	} else {
+		return nil, fmt.Errorf("query returned %d fields, use 'field' to select the recovery point", len(record))
+	}
+	return parseRecoveryTime(value)
+}
+
+func parseRecoveryTime(value interface{}) (*time.Time, error) {
+	switch v := value.(type) {
+	case string:
+		for _, layout := range recoveryTimeLayouts {
+			t, err := time.Parse(layout, v)
+			if err == nil {
+				return &t, nil
+			}
+		}
+		return nil, fmt.Errorf("unable to parse recovery point '%s' as time", v)
+	case float64:
+		t := time.Unix(int64(v), 0)
+		return &t, nil
+	case map[string]interface{}:
+		// Extended JSON date
+		if date, ok := v["$date"]; ok {
+			return parseRecoveryTime(date)
+		}
+	case nil:
+		return nil, fmt.Errorf("recovery point is empty")
+	}
+	return nil, fmt.Errorf("unable to parse recovery point '%v' as time", value)
+}
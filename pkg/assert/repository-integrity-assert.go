@@ -0,0 +1,78 @@
+package assert
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+)
+
+type RepositoryIntegrityAssert struct {
+}
+
+func (a RepositoryIntegrityAssert) Type() string {
+	return "repositoryIntegrity"
+}
+
+func (a RepositoryIntegrityAssert) RunFor(assert *AssertConfig) bool {
+	return assert.RepositoryIntegrity != nil
+}
+
+func (a RepositoryIntegrityAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
+	config := assertConfig.RepositoryIntegrity
+	resticBackupProvider, ok := backupProvider.(backup.ResticBackupProvider)
+	if !ok {
+		return Error(errors.New("repositoryIntegrity is only supported for restic repositories"))
+	}
+
+	// Rotate the subset every day, so the whole repository is read once every N days
+	readDataSubset := config.ReadDataSubset
+	if config.RotateSubsets != nil && *config.RotateSubsets > 0 {
+		day := time.Now().Unix() / int64((24 * time.Hour).Seconds())
+		subset := fmt.Sprintf("%d/%d", day%int64(*config.RotateSubsets)+1, *config.RotateSubsets)
+		readDataSubset = &subset
+	}
+
+	result, err := resticBackupProvider.Check(testName, dir, readDataSubset)
+	if err != nil {
+		return Error(err)
+	}
+	if result.DataRead != nil {
+		log.Printf("[%s] Repository check: %s", testName, *result.DataRead)
+	}
+	if result.PacksRead != nil {
+		log.Printf("[%s] Repository check: read %s packs", testName, *result.PacksRead)
+	}
+
+	problems := make([]string, 0)
+	if len(result.MissingPacks) > 0 {
+		problems = append(problems, fmt.Sprintf("missing packs: %s", strings.Join(result.MissingPacks, "; ")))
+	}
+	if len(result.UnreferencedBlobs) > 0 && !config.IgnoreUnreferenced {
+		problems = append(problems, fmt.Sprintf("unreferenced blobs: %s", strings.Join(result.UnreferencedBlobs, "; ")))
+	}
+	if len(result.LockErrors) > 0 {
+		problems = append(problems, fmt.Sprintf("lock problems: %s", strings.Join(result.LockErrors, "; ")))
+	}
+	if len(result.Errors) > 0 {
+		problems = append(problems, fmt.Sprintf("errors: %s", strings.Join(result.Errors, "; ")))
+	}
+
+	if len(problems) > 0 {
+		msg := "Repository check failed: " + strings.Join(problems, ", ")
+		if result.PacksRead != nil {
+			msg += fmt.Sprintf(" (read %s packs)", *result.PacksRead)
+		}
+		return Fail(msg)
+	}
+	return Pass()
+}
+
+func NewRepositoryIntegrityAssert() RepositoryIntegrityAssert {
+	repositoryIntegrityAssert := RepositoryIntegrityAssert{}
+	return repositoryIntegrityAssert
+}
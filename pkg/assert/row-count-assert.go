@@ -0,0 +1,119 @@
+package assert
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/MaxxtonGroup/backup-validator/pkg/pattern"
+)
+
+type RowCountAssert struct {
+}
+
+func (a RowCountAssert) Type() string {
+	return "rowCount"
+}
+
+func (a RowCountAssert) RunFor(assert *AssertConfig) bool {
+	return assert.RowCount != nil
+}
+
+func (a RowCountAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
+	config := assertConfig.RowCount
+	if config.Equals == nil && config.Min == nil && config.Max == nil {
+		return Error(fmt.Errorf("rowCount of %s needs at least one of: min, max or equals", config.Database))
+	}
+	databases, err := formatProvider.ListDatabases(testName)
+	if err != nil {
+		return Error(err)
+	}
+
+	matchingDatabases, err := pattern.Match(config.Database, databases)
+	if err != nil {
+		return Error(err)
+	}
+	if len(matchingDatabases) == 0 {
+		return Fail(fmt.Sprintf("No databases matching %s", config.Database))
+	}
+
+	expected := []string{}
+	if config.Equals != nil {
+		expected = append(expected, fmt.Sprintf("= %d", *config.Equals))
+	}
+	if config.Min != nil {
+		expected = append(expected, fmt.Sprintf(">= %d", *config.Min))
+	}
+	if config.Max != nil {
+		expected = append(expected, fmt.Sprintf("<= %d", *config.Max))
+	}
+
+	// Postgres tables are schema qualified, but may still be referenced without the schema
+	_, schemaQualified := formatProvider.(format.PostgresqlFormatProvider)
+
+	details := []*Detail{}
+	for _, db := range matchingDatabases {
+		// Without tables the records of the database itself are counted (eg. documents in an elasticsearch index)
+		tables := []string{""}
+		if config.Tables != nil {
+			allTables, err := formatProvider.ListTables(testName, db)
+			if err != nil {
+				details = append(details, &Detail{Database: db, Status: StatusError, Message: err.Error()})
+				continue
+			}
+			tables = []string{}
+			for _, tablePattern := range *config.Tables {
+				matchingTables, err := matchTables(tablePattern, allTables, schemaQualified)
+				if err != nil {
+					return Error(err)
+				}
+				if len(matchingTables) == 0 {
+					details = append(details, &Detail{Database: db, Status: StatusFail, Message: "no tables matching " + tablePattern})
+					continue
+				}
+				if len(matchingTables) > 1 && !strings.Contains(tablePattern, "*") && !strings.HasPrefix(tablePattern, "/") {
+					details = append(details, &Detail{Database: db, Status: StatusFail, Message: fmt.Sprintf("table %s is ambiguous (%s), add the schema", tablePattern, strings.Join(matchingTables, ", "))})
+					continue
+				}
+				tables = append(tables, matchingTables...)
+			}
+		}
+
+		for _, table := range tables {
+			name := db
+			if table != "" {
+				name = db + "." + table
+			}
+			count, err := formatProvider.CountRecords(testName, db, table, config.Estimate)
+			if err != nil {
+				details = append(details, &Detail{Database: name, Status: StatusError, Message: err.Error()})
+				continue
+			}
+			history.Record("rowCount:"+name, float64(*count))
+
+			detail := &Detail{
+				Database: name,
+				Status:   StatusFail,
+				Expected: strings.Join(expected, ", "),
+				Actual:   fmt.Sprintf("%d", *count),
+			}
+			if config.Equals != nil && *count != *config.Equals {
+				detail.Message = fmt.Sprintf("has %d rows, expected %d", *count, *config.Equals)
+			} else if config.Min != nil && *count < *config.Min {
+				detail.Message = fmt.Sprintf("has %d rows, expected at least %d", *count, *config.Min)
+			} else if config.Max != nil && *count > *config.Max {
+				detail.Message = fmt.Sprintf("has %d rows, expected at most %d", *count, *config.Max)
+			} else {
+				detail.Status = StatusPass
+			}
+			details = append(details, detail)
+		}
+	}
+	return ResultOf(details, "Row count mismatch")
+}
+
+func NewRowCountAssert() RowCountAssert {
+	rowCountAssert := RowCountAssert{}
+	return rowCountAssert
+}
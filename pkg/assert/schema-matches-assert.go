@@ -0,0 +1,81 @@
+package assert
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/MaxxtonGroup/backup-validator/pkg/pattern"
+	"github.com/MaxxtonGroup/backup-validator/pkg/schema"
+)
+
+type SchemaMatchesAssert struct {
+}
+
+func (a SchemaMatchesAssert) Type() string {
+	return "schemaMatches"
+}
+
+func (a SchemaMatchesAssert) RunFor(assert *AssertConfig) bool {
+	return assert.SchemaMatches != nil
+}
+
+func (a SchemaMatchesAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
+	config := assertConfig.SchemaMatches
+	expectedSchema, err := schema.Load(config.File)
+	if err != nil {
+		return Error(err)
+	}
+
+	expectedDatabases := []string{}
+	for database := range expectedSchema.Databases {
+		expectedDatabases = append(expectedDatabases, database)
+	}
+	sort.Strings(expectedDatabases)
+	if config.Database != nil {
+		expectedDatabases, err = pattern.Match(*config.Database, expectedDatabases)
+		if err != nil {
+			return Error(err)
+		}
+	}
+	if len(expectedDatabases) == 0 {
+		return Fail(fmt.Sprintf("No databases to compare in %s", config.File))
+	}
+
+	databases, err := formatProvider.ListDatabases(testName)
+	if err != nil {
+		return Error(err)
+	}
+	existingDatabases := map[string]bool{}
+	for _, database := range databases {
+		existingDatabases[database] = true
+	}
+
+	allowExtra := config.AllowExtra == nil || *config.AllowExtra
+	diff := []string{}
+	for _, database := range expectedDatabases {
+		if !existingDatabases[database] {
+			diff = append(diff, "- database "+database)
+			continue
+		}
+		actualSchema, err := schema.Take(testName, formatProvider, []string{database})
+		if err != nil {
+			return Error(err)
+		}
+		for _, line := range schema.Diff(expectedSchema.Databases[database], actualSchema.Databases[database], allowExtra) {
+			diff = append(diff, line[:2]+database+"."+line[2:])
+		}
+	}
+
+	if len(diff) > 0 {
+		return Fail(fmt.Sprintf("Schema doesn't match %s:\n%s", config.File, strings.Join(diff, "\n")))
+	}
+	return Pass()
+}
+
+func NewSchemaMatchesAssert() SchemaMatchesAssert {
+	schemaMatchesAssert := SchemaMatchesAssert{}
+	return schemaMatchesAssert
+}
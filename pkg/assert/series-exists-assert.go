@@ -0,0 +1,68 @@
+package assert
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+)
+
+type SeriesExistsAssert struct {
+}
+
+func (a SeriesExistsAssert) Type() string {
+	return "seriesExists"
+}
+
+func (a SeriesExistsAssert) RunFor(assert *AssertConfig) bool {
+	return assert.SeriesExists != nil
+}
+
+func (a SeriesExistsAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
+	config := assertConfig.SeriesExists
+	seriesProvider, ok := formatProvider.(format.SeriesProvider)
+	if !ok {
+		return Error(errors.New("seriesExists: format doesn't support time series"))
+	}
+
+	window := 24 * time.Hour
+	if config.Window != nil {
+		var err error
+		window, err = time.ParseDuration(*config.Window)
+		if err != nil {
+			return Error(err)
+		}
+	}
+
+	// The window ends at the snapshot time or the current time
+	referenceName := "snapshot"
+	referenceTime := snapshot.Time
+	if config.RelativeTo != nil && *config.RelativeTo == "now" {
+		referenceName = "current"
+		referenceTime = time.Now()
+	}
+	from := referenceTime.Add(-window)
+
+	count, err := seriesProvider.CountSamples(testName, config.Database, config.Series, from, referenceTime)
+	if err != nil {
+		return Error(err)
+	}
+	log.Printf("[%s] Found %d samples for %s within %s before the %s time", testName, *count, config.Series, window, referenceName)
+
+	minSamples := uint64(1)
+	if config.MinSamples != nil {
+		minSamples = *config.MinSamples
+	}
+	if *count < minSamples {
+		return Fail(fmt.Sprintf("Found %d samples for %s within %s before the %s time, expected at least %d", *count, config.Series, window, referenceName, minSamples)).WithValues(fmt.Sprintf(">= %d samples", minSamples), fmt.Sprintf("%d samples", *count))
+	}
+	return Pass().WithValues(fmt.Sprintf(">= %d samples", minSamples), fmt.Sprintf("%d samples", *count))
+}
+
+func NewSeriesExistsAssert() SeriesExistsAssert {
+	seriesExistsAssert := SeriesExistsAssert{}
+	return seriesExistsAssert
+}
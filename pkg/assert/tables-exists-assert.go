@@ -1,66 +1,107 @@
 package assert
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
 	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/MaxxtonGroup/backup-validator/pkg/pattern"
 )
 
 type TablesExistsAssert struct {
 }
 
+func (a TablesExistsAssert) Type() string {
+	return "tablesExists"
+}
+
 func (a TablesExistsAssert) RunFor(assert *AssertConfig) bool {
 	return assert.TablesExists != nil
 }
 
-func (a TablesExistsAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot) *string {
+func (a TablesExistsAssert) Run(testName string, dir string, assertConfig *AssertConfig, backupProvider backup.BackupProvider, formatProvider format.FormatProvider, timings Timings, snapshot *backup.Snapshot, history *History) *Result {
 	var err error
 	databases, err := formatProvider.ListDatabases(testName)
 	if err != nil {
-		msg := err.Error()
-		return &msg
+		return Error(err)
 	}
 
 	databaseName := assertConfig.TablesExists.Database
-	matchingDatabases, err := getMatchingDatabases(testName, databaseName, databases, formatProvider, *snapshot)
+	matchingDatabases, err := pattern.Match(databaseName, databases)
 	if err != nil {
-		msg := err.Error()
-		return &msg
+		return Error(err)
 	}
 
-	var msg string
+	if len(matchingDatabases) == 0 {
+		return Fail(fmt.Sprintf("No databases found that match %s", databaseName))
+	}
+
+	// Postgres tables are schema qualified, but may still be referenced without the schema
+	_, schemaQualified := formatProvider.(format.PostgresqlFormatProvider)
+
+	// One of the databases should have all tables
+	details := []*Detail{}
 	for _, db := range matchingDatabases {
 		tables, err := formatProvider.ListTables(testName, db)
 		if err != nil {
-			msg = err.Error()
+			details = append(details, &Detail{Database: db, Status: StatusError, Message: err.Error()})
 			continue
 		}
 
 		missingTables := make([]string, 0)
 		for _, tableName := range *assertConfig.TablesExists.Tables {
-			exists := false
-			for _, table := range tables {
-				if table == tableName {
-					exists = true
-				}
+			exists, err := tableExists(tableName, tables, schemaQualified)
+			if err != nil {
+				return Error(err)
 			}
 			if !exists {
 				missingTables = append(missingTables, tableName)
 			}
 		}
 
+		detail := &Detail{Database: db, Status: StatusPass}
 		if len(missingTables) > 0 {
-			msg = "Missing tables: " + strings.Join(missingTables, ", ")
-		} else {
-			return nil
+			detail.Status = StatusFail
+			detail.Message = strings.Join(missingTables, ", ")
 		}
+		details = append(details, detail)
 	}
+	return AnyOf(details, "Missing tables")
+}
 
-	if msg != "" {
-		return &msg
+// tableExists checks if a table matches the name, which may contain '*' wildcards or be a /regex/
+func tableExists(tableName string, tables []string, schemaQualified bool) (bool, error) {
+	matchingTables, err := matchTables(tableName, tables, schemaQualified)
+	if err != nil {
+		return false, err
+	}
+	return len(matchingTables) > 0, nil
+}
+
+// matchTables returns the tables that match the name, which may contain '*' wildcards or be a /regex/.
+// A plain name matches exactly, a postgres table without schema matches the table in the public schema, or otherwise in any schema.
+func matchTables(tableName string, tables []string, schemaQualified bool) ([]string, error) {
+	if strings.Contains(tableName, "*") || (len(tableName) >= 2 && strings.HasPrefix(tableName, "/") && strings.HasSuffix(tableName, "/")) {
+		return pattern.Match(tableName, tables)
+	}
+
+	matchingTables := []string{}
+	for _, table := range tables {
+		if table == tableName {
+			return []string{table}, nil
+		}
+		if schemaQualified && !strings.Contains(tableName, ".") {
+			parts := strings.SplitN(table, ".", 2)
+			if len(parts) == 2 && parts[1] == tableName {
+				if parts[0] == "public" {
+					return []string{table}, nil
+				}
+				matchingTables = append(matchingTables, table)
+			}
+		}
 	}
-	return nil
+	return matchingTables, nil
 }
 
 func NewTablesExistsAssert() TablesExistsAssert {
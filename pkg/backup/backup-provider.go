@@ -12,4 +12,7 @@ type Snapshot struct {
 	Name      string
 	Time      time.Time
 	Databases []string
+
+	// Databases that are restored by the backup provider, when it restores a selection of the databases
+	RestoredDatabases []string
 }
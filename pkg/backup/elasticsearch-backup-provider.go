@@ -1,19 +1,27 @@
 package backup
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
+	"net/http"
+	"net/url"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
-	"github.com/MaxxtonGroup/backup-validator/pkg/runtime"
-	"github.com/dustin/go-humanize"
+	"github.com/MaxxtonGroup/backup-validator/pkg/elasticsearch"
+	"github.com/MaxxtonGroup/backup-validator/pkg/pattern"
 )
 
+// elasticsearchRecoveryInterval is the time between checks of the restore progress
+var elasticsearchRecoveryInterval = 5 * time.Second
+
 type ElasticsearchBackupProvider struct {
-	runtimeProvider runtime.RuntimeProvider
+	client         *elasticsearch.Client
+	repository     string
+	snapshotConfig elasticsearch.SnapshotConfig
 }
 
 type ElasticsearchSnapshotResponse struct {
@@ -28,11 +36,15 @@ type ElasticsearchSnapshot struct {
 }
 
 type ElastcisearchRestoreOptions struct {
-	Indices string `json:"indices"`
+	Indices            string `json:"indices,omitempty"`
+	IncludeGlobalState *bool  `json:"include_global_state,omitempty"`
+	RenamePattern      string `json:"rename_pattern,omitempty"`
+	RenameReplacement  string `json:"rename_replacement,omitempty"`
+	Partial            bool   `json:"partial,omitempty"`
 }
 
 type ElasticsearchRestore struct {
-	Index          string `json:"string"`
+	Index          string `json:"index"`
 	Type           string `json:"type"`
 	Stage          string `json:"stage"`
 	Repository     string `json:"repository"`
@@ -54,34 +66,35 @@ func (p ElasticsearchBackupProvider) Restore(testName string, dir string, snapsh
 			restoreOptions.Indices = value
 		}
 	}
-	if restoreOptions.Indices != "" {
-		restoreOptions.Indices = snapshot.Time.Add(-(24 * time.Hour)).Format(restoreOptions.Indices)
+	if p.snapshotConfig.Indices != nil {
+		restoreOptions.Indices = *p.snapshotConfig.Indices
 	}
-
-	restoreOptionsString, err := json.Marshal(&restoreOptions)
+	indices, err := pattern.Render(restoreOptions.Indices, pattern.NewData(testName, snapshot.Time))
 	if err != nil {
 		return err
 	}
+	restoreOptions.Indices = indices
+	restoreOptions.IncludeGlobalState = p.snapshotConfig.IncludeGlobalState
+	if p.snapshotConfig.RenamePattern != nil {
+		restoreOptions.RenamePattern = *p.snapshotConfig.RenamePattern
+		if p.snapshotConfig.RenameReplacement != nil {
+			restoreOptions.RenameReplacement = *p.snapshotConfig.RenameReplacement
+		}
+	}
+	restoreOptions.Partial = p.snapshotConfig.Partial
 
-	output, err := p.runtimeProvider.Exec(testName, "curl", "--output", "/dev/stdout", "--write-out", "%{http_code}", "-X", "POST", "http://localhost:9200/_snapshot/backup/"+snapshot.Name+"/_restore", "-H", "Content-Type: application/json", "-d", string(restoreOptionsString))
+	err = p.client.Request(testName, http.MethodPost, p.snapshotPath(snapshot.Name)+"/_restore", restoreOptions, nil)
 	if err != nil {
 		return err
 	}
-	if !strings.HasSuffix(*output, "200") {
-		return fmt.Errorf("the requested URL returned error: %s", *output)
-	}
 
 	// Wait for recovery to complete
 	var previousProgress string
 	for {
-		time.Sleep(5 * time.Second)
-		output, err := p.runtimeProvider.Exec(testName, "curl", "http://localhost:9200/_cat/recovery?format=json")
-		if err != nil {
-			return err
-		}
-
+		time.Sleep(elasticsearchRecoveryInterval)
+		// Request the sizes in bytes, the human readable units differ between elasticsearch and opensearch versions
 		esRestores := []*ElasticsearchRestore{}
-		err = json.Unmarshal([]byte(*output), &esRestores)
+		err := p.client.Get(testName, "/_cat/recovery?format=json&bytes=b", &esRestores)
 		if err != nil {
 			return err
 		}
@@ -89,14 +102,16 @@ func (p ElasticsearchBackupProvider) Restore(testName string, dir string, snapsh
 		bytesTotal := float64(0)
 		bytesRecovered := float64(0)
 		done := true
+		restoredIndices := map[string]bool{}
 		for _, restore := range esRestores {
-			if restore.Repository == "backup" && restore.Snapshot == snapshot.Name {
-				bTotal, err := humanize.ParseBytes(restore.BytesTotal)
+			if restore.Repository == p.repository && restore.Snapshot == snapshot.Name {
+				restoredIndices[restore.Index] = true
+				bTotal, err := strconv.ParseUint(restore.BytesTotal, 10, 64)
 				if err != nil {
 					return err
 				}
 				bytesTotal += float64(bTotal)
-				bRecovered, err := humanize.ParseBytes(restore.BytesRecovered)
+				bRecovered, err := strconv.ParseUint(restore.BytesRecovered, 10, 64)
 				if err != nil {
 					return err
 				}
@@ -111,7 +126,14 @@ func (p ElasticsearchBackupProvider) Restore(testName string, dir string, snapsh
 			log.Printf("[%s] Restoring %s", testName, newProgress)
 			previousProgress = newProgress
 		}
+		// Keep the names of the restored indices, which may be renamed, for the report and to delete them from an external cluster
+		snapshot.RestoredDatabases = []string{}
+		for index := range restoredIndices {
+			snapshot.RestoredDatabases = append(snapshot.RestoredDatabases, index)
+		}
+		sort.Strings(snapshot.RestoredDatabases)
 		if done {
+			log.Printf("[%s] Restored indices: %s", testName, strings.Join(snapshot.RestoredDatabases, ", "))
 			break
 		}
 	}
@@ -120,20 +142,27 @@ func (p ElasticsearchBackupProvider) Restore(testName string, dir string, snapsh
 
 func (p ElasticsearchBackupProvider) ListSnapshots(testName string, dir string) ([]*Snapshot, error) {
 	log.Printf("[%s] List snapshots...\n", testName)
-	output, err := p.runtimeProvider.Exec(testName, "curl", "-X", "GET", "http://localhost:9200/_snapshot/backup/_all")
+	esSnapshotResponse := &ElasticsearchSnapshotResponse{}
+	err := p.client.Get(testName, p.snapshotPath("_all"), esSnapshotResponse)
 	if err != nil {
 		return nil, err
 	}
 
-	esSnapshotResponse := &ElasticsearchSnapshotResponse{}
-	err = json.Unmarshal([]byte(*output), &esSnapshotResponse)
-	if err != nil {
-		return nil, err
+	var nameRegex *regexp.Regexp
+	if p.snapshotConfig.Name != nil {
+		nameRegex, err = pattern.Compile(*p.snapshotConfig.Name)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	snapshots := make([]*Snapshot, 0)
 	for _, esSnapshot := range esSnapshotResponse.Snapshots {
-		if esSnapshot.State == "SUCCESS" {
+		if nameRegex != nil && !nameRegex.MatchString(esSnapshot.Snapshot) {
+			continue
+		}
+		// Partial snapshots miss shards that failed during the snapshot, they are only restored when allowed
+		if esSnapshot.State == "SUCCESS" || (esSnapshot.State == "PARTIAL" && p.snapshotConfig.Partial) {
 			startTime, err := time.Parse(time.RFC3339, esSnapshot.StartTime)
 			if err != nil {
 				return nil, err
@@ -152,9 +181,15 @@ func (p ElasticsearchBackupProvider) ListSnapshots(testName string, dir string)
 	return snapshots, nil
 }
 
-func NewElasticsearchBackupProvider(runtimeProvider runtime.RuntimeProvider) ElasticsearchBackupProvider {
+func (p ElasticsearchBackupProvider) snapshotPath(snapshot string) string {
+	return "/_snapshot/" + url.PathEscape(p.repository) + "/" + url.PathEscape(snapshot)
+}
+
+func NewElasticsearchBackupProvider(client *elasticsearch.Client, repository string, snapshotConfig elasticsearch.SnapshotConfig) ElasticsearchBackupProvider {
 	elasticsearchBackupProvider := ElasticsearchBackupProvider{
-		runtimeProvider: runtimeProvider,
+		client:         client,
+		repository:     repository,
+		snapshotConfig: snapshotConfig,
 	}
 	return elasticsearchBackupProvider
 }
@@ -0,0 +1,235 @@
+package backup
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/elasticsearch"
+	"github.com/MaxxtonGroup/backup-validator/pkg/elasticsearch/elasticsearchtest"
+)
+
+const recordedResponses = "../elasticsearch/testdata"
+
+func newTestElasticsearchBackupProvider(t *testing.T, url string, flavourName string, snapshotConfig elasticsearch.SnapshotConfig) ElasticsearchBackupProvider {
+	retries := 0
+	flavour, err := elasticsearch.NewFlavour(elasticsearch.Config{Flavour: &flavourName, Url: &url, Retries: &retries}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := elasticsearch.NewClient(nil, *flavour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewElasticsearchBackupProvider(client, elasticsearch.DefaultRepository, snapshotConfig)
+}
+
+func snapshotNames(snapshots []*Snapshot) []string {
+	names := []string{}
+	for _, snapshot := range snapshots {
+		names = append(names, snapshot.Name)
+	}
+	return names
+}
+
+func TestElasticsearchListSnapshots(t *testing.T) {
+	nightly := "nightly-*"
+	regex := "/^nightly-2024\\.01\\.0[12]$/"
+	tests := []struct {
+		name           string
+		flavour        string
+		snapshotConfig elasticsearch.SnapshotConfig
+		expected       []string
+	}{
+		{
+			name:     "elasticsearch successful snapshots",
+			flavour:  "elasticsearch",
+			expected: []string{"nightly-2024.01.01", "nightly-2024.01.02", "manual-before-upgrade"},
+		},
+		{
+			name:           "elasticsearch partial snapshots",
+			flavour:        "elasticsearch",
+			snapshotConfig: elasticsearch.SnapshotConfig{Partial: true},
+			expected:       []string{"nightly-2024.01.01", "nightly-2024.01.02", "nightly-2024.01.03", "manual-before-upgrade"},
+		},
+		{
+			name:           "elasticsearch wildcard name",
+			flavour:        "elasticsearch",
+			snapshotConfig: elasticsearch.SnapshotConfig{Name: &nightly, Partial: true},
+			expected:       []string{"nightly-2024.01.01", "nightly-2024.01.02", "nightly-2024.01.03"},
+		},
+		{
+			name:           "elasticsearch regex name",
+			flavour:        "elasticsearch",
+			snapshotConfig: elasticsearch.SnapshotConfig{Name: &regex, Partial: true},
+			expected:       []string{"nightly-2024.01.01", "nightly-2024.01.02"},
+		},
+		{
+			name:     "opensearch snapshot in progress",
+			flavour:  "opensearch",
+			expected: []string{"nightly-2024.01.01", "nightly-2024.01.02"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := elasticsearchtest.NewServer(t, recordedResponses, map[string][]elasticsearchtest.Response{
+				"GET /_snapshot/backup/_all": {{Status: http.StatusOK, File: test.flavour + "/snapshots.json"}},
+			})
+			provider := newTestElasticsearchBackupProvider(t, server.URL, test.flavour, test.snapshotConfig)
+
+			snapshots, err := provider.ListSnapshots("test", t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if names := snapshotNames(snapshots); !reflect.DeepEqual(names, test.expected) {
+				t.Errorf("expected snapshots %v, got %v", test.expected, names)
+			}
+		})
+	}
+}
+
+func TestElasticsearchListSnapshotsParsesSnapshot(t *testing.T) {
+	server := elasticsearchtest.NewServer(t, recordedResponses, map[string][]elasticsearchtest.Response{
+		"GET /_snapshot/backup/_all": {{Status: http.StatusOK, File: "elasticsearch/snapshots.json"}},
+	})
+	provider := newTestElasticsearchBackupProvider(t, server.URL, "elasticsearch", elasticsearch.SnapshotConfig{})
+
+	snapshots, err := provider.ListSnapshots("test", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := snapshots[1]
+	expectedTime := time.Date(2024, 1, 2, 2, 0, 0, 412000000, time.UTC)
+	if !snapshot.Time.Equal(expectedTime) {
+		t.Errorf("expected time %s, got %s", expectedTime, snapshot.Time)
+	}
+	if !reflect.DeepEqual(snapshot.Databases, []string{"logs-2024.01.02", "customers"}) {
+		t.Errorf("unexpected indices %v", snapshot.Databases)
+	}
+}
+
+func TestElasticsearchListSnapshotsErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		flavour  string
+		response elasticsearchtest.Response
+		message  string
+	}{
+		{
+			name:     "elasticsearch repository missing",
+			flavour:  "elasticsearch",
+			response: elasticsearchtest.Response{Status: http.StatusNotFound, File: "elasticsearch/error-repository-missing.json"},
+			message:  "repository_missing_exception: [backup] missing",
+		},
+		{
+			name:     "opensearch without permissions",
+			flavour:  "opensearch",
+			response: elasticsearchtest.Response{Status: http.StatusForbidden, File: "opensearch/error-forbidden.json"},
+			message:  "security_exception: no permissions for [cluster:admin/snapshot/get]",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := elasticsearchtest.NewServer(t, recordedResponses, map[string][]elasticsearchtest.Response{
+				"GET /_snapshot/backup/_all": {test.response},
+			})
+			provider := newTestElasticsearchBackupProvider(t, server.URL, test.flavour, elasticsearch.SnapshotConfig{})
+
+			_, err := provider.ListSnapshots("test", t.TempDir())
+			if err == nil || !strings.Contains(err.Error(), test.message) {
+				t.Errorf("expected an error with %q, got %v", test.message, err)
+			}
+		})
+	}
+}
+
+func TestElasticsearchRestore(t *testing.T) {
+	elasticsearchRecoveryInterval = time.Millisecond
+	renamePattern := "(.+)"
+	renameReplacement := "restored-$1"
+	tests := []struct {
+		name           string
+		flavour        string
+		snapshotConfig elasticsearch.SnapshotConfig
+		recovery       []elasticsearchtest.Response
+		restored       []string
+	}{
+		{
+			name:           "elasticsearch renamed indices",
+			flavour:        "elasticsearch",
+			snapshotConfig: elasticsearch.SnapshotConfig{RenamePattern: &renamePattern, RenameReplacement: &renameReplacement},
+			recovery: []elasticsearchtest.Response{
+				{Status: http.StatusOK, File: "elasticsearch/recovery-in-progress.json"},
+				{Status: http.StatusOK, File: "elasticsearch/recovery-done.json"},
+			},
+			restored: []string{"restored-customers", "restored-logs-2024.01.02"},
+		},
+		{
+			name:    "opensearch",
+			flavour: "opensearch",
+			recovery: []elasticsearchtest.Response{
+				{Status: http.StatusOK, File: "opensearch/recovery-done.json"},
+			},
+			restored: []string{"customers", "logs-2024.01.02"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restoreKey := "POST /_snapshot/backup/nightly-2024.01.02/_restore"
+			recoveryKey := "GET /_cat/recovery?format=json&bytes=b"
+			server := elasticsearchtest.NewServer(t, recordedResponses, map[string][]elasticsearchtest.Response{
+				restoreKey:  {{Status: http.StatusOK, File: test.flavour + "/restore.json"}},
+				recoveryKey: test.recovery,
+			})
+			provider := newTestElasticsearchBackupProvider(t, server.URL, test.flavour, test.snapshotConfig)
+
+			snapshot := &Snapshot{Name: "nightly-2024.01.02", Time: time.Date(2024, 1, 2, 2, 0, 0, 0, time.UTC)}
+			err := provider.Restore("test", t.TempDir(), snapshot, []string{"indices=customers,logs-*"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(snapshot.RestoredDatabases, test.restored) {
+				t.Errorf("expected restored indices %v, got %v", test.restored, snapshot.RestoredDatabases)
+			}
+			if calls := server.Calls(recoveryKey); calls != len(test.recovery) {
+				t.Errorf("expected %d recovery checks, got %d", len(test.recovery), calls)
+			}
+
+			bodies := server.Bodies(restoreKey)
+			if len(bodies) != 1 {
+				t.Fatalf("expected 1 restore request, got %d", len(bodies))
+			}
+			restoreOptions := ElastcisearchRestoreOptions{}
+			err = json.Unmarshal([]byte(bodies[0]), &restoreOptions)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if restoreOptions.Indices != "customers,logs-*" {
+				t.Errorf("expected indices customers,logs-*, got %s", restoreOptions.Indices)
+			}
+			if test.snapshotConfig.RenamePattern != nil && restoreOptions.RenameReplacement != renameReplacement {
+				t.Errorf("expected rename replacement %s, got %s", renameReplacement, restoreOptions.RenameReplacement)
+			}
+		})
+	}
+}
+
+func TestElasticsearchRestoreError(t *testing.T) {
+	restoreKey := "POST /_snapshot/backup/nightly-2024.01.02/_restore"
+	server := elasticsearchtest.NewServer(t, recordedResponses, map[string][]elasticsearchtest.Response{
+		restoreKey: {{Status: http.StatusInternalServerError, File: "elasticsearch/error-index-exists.json"}},
+	})
+	provider := newTestElasticsearchBackupProvider(t, server.URL, "elasticsearch", elasticsearch.SnapshotConfig{})
+
+	snapshot := &Snapshot{Name: "nightly-2024.01.02", Time: time.Date(2024, 1, 2, 2, 0, 0, 0, time.UTC)}
+	err := provider.Restore("test", t.TempDir(), snapshot, []string{})
+	if err == nil || !strings.Contains(err.Error(), "cannot restore index [customers]") {
+		t.Errorf("expected the restore error, got %v", err)
+	}
+	if calls := server.Calls(restoreKey); calls != 1 {
+		t.Errorf("expected 1 restore request, got %d", calls)
+	}
+}
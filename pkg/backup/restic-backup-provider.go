@@ -8,37 +8,37 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strings"
 	"time"
 )
 
+var resticPacksReadRegex = regexp.MustCompile(`(\d+) / (\d+) packs`)
+
 type ResticBackupProvider struct {
 	config ResticConfig
 }
 
+type ResticCheckResult struct {
+	MissingPacks      []string
+	UnreferencedBlobs []string
+	LockErrors        []string
+	Errors            []string
+	DataRead          *string
+	PacksRead         *string
+}
+
 // Restore Restic snapshot
 func (p ResticBackupProvider) Restore(testName string, dir string, snapshot *Snapshot, importOptions []string) error {
 	log.Printf("[%s] Restoring backup %s from %s...\n", testName, snapshot.Name, p.config.Repository)
 
-	// store password
-	if p.config.Password != nil {
-		p.config.PasswordFile = filepath.Join(dir, "password")
-		err := ioutil.WriteFile(filepath.Join(dir, "password"), []byte(*p.config.Password), 0600)
-		if err != nil {
-			return err
-		}
-		defer os.Remove(p.config.PasswordFile)
-	}
-
 	// create command
-	cmd := exec.Command("restic", "restore", "--verify", "--repo", p.config.Repository, "--password-file", p.config.PasswordFile, "--target", filepath.Join(dir, "workdir"), "latest")
-	env := os.Environ()
-	if p.config.Env != nil {
-		for key, value := range p.config.Env {
-			env = append(env, key+"="+value)
-		}
+	cmd, cleanup, err := p.command(dir, "restore", "--verify", "--target", filepath.Join(dir, "workdir"), "latest")
+	if err != nil {
+		return err
 	}
-	cmd.Env = env
+	defer cleanup()
 
 	// run command
 	stderr, err := cmd.StderrPipe()
@@ -60,31 +60,18 @@ func (p ResticBackupProvider) Restore(testName string, dir string, snapshot *Sna
 
 // Restore Restic snapshot
 func (p ResticBackupProvider) ListSnapshots(testName string, dir string) ([]*Snapshot, error) {
-	// store password
-	if p.config.Password != nil {
-		p.config.PasswordFile = filepath.Join(dir, "password")
-		err := ioutil.WriteFile(filepath.Join(dir, "password"), []byte(*p.config.Password), 0600)
-		if err != nil {
-			return nil, err
-		}
-		defer os.Remove(p.config.PasswordFile)
-	}
-
 	// create command
-	cmd := exec.Command("restic", "snapshots", "--json", "--repo", p.config.Repository, "--password-file", p.config.PasswordFile)
-	env := os.Environ()
-	if p.config.Env != nil {
-		for key, value := range p.config.Env {
-			env = append(env, key+"="+value)
-		}
+	cmd, cleanup, err := p.command(dir, "snapshots", "--json")
+	if err != nil {
+		return nil, err
 	}
-	cmd.Env = env
+	defer cleanup()
 
 	// Set output to Byte Buffers
 	var outb, errb bytes.Buffer
 	cmd.Stdout = &outb
 	cmd.Stderr = &errb
-	err := cmd.Run()
+	err = cmd.Run()
 	if err != nil {
 		log.Printf("[%s] Restic: %s", testName, errb.String())
 		return nil, err
@@ -111,6 +98,100 @@ func (p ResticBackupProvider) ListSnapshots(testName string, dir string) ([]*Sna
 	return snapshots, nil
 }
 
+// Check the integrity of the Restic repository, readDataSubset (eg. 10% or 1/5) also verifies the content of the data packs
+func (p ResticBackupProvider) Check(testName string, dir string, readDataSubset *string) (*ResticCheckResult, error) {
+	args := []string{"check"}
+	if readDataSubset != nil {
+		args = append(args, "--read-data-subset="+*readDataSubset)
+	}
+	log.Printf("[%s] Checking repository %s...\n", testName, p.config.Repository)
+
+	// create command
+	cmd, cleanup, err := p.command(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	// restic check exits with an error when the repository contains errors, so only fail when it didn't run
+	var outb bytes.Buffer
+	cmd.Stdout = &outb
+	cmd.Stderr = &outb
+	err = cmd.Run()
+	if _, ok := err.(*exec.ExitError); err != nil && !ok {
+		return nil, err
+	}
+
+	// parse output
+	result := &ResticCheckResult{}
+	for _, line := range strings.Split(outb.String(), "\n") {
+		line = strings.TrimSpace(line)
+		lowerLine := strings.ToLower(line)
+		switch {
+		case line == "":
+		case strings.Contains(lowerLine, "lock") && containsAny(lowerLine, "locked", "unable", "failed", "error", "stale"):
+			result.LockErrors = append(result.LockErrors, line)
+		case strings.Contains(lowerLine, "not referenced") || strings.Contains(lowerLine, "unused") || strings.Contains(lowerLine, "additional files"):
+			result.UnreferencedBlobs = append(result.UnreferencedBlobs, line)
+		case strings.Contains(lowerLine, "not found") || strings.Contains(lowerLine, "does not exist") || strings.Contains(lowerLine, "missing"):
+			result.MissingPacks = append(result.MissingPacks, line)
+		case strings.HasPrefix(lowerLine, "read "):
+			result.DataRead = &line
+		case strings.HasPrefix(lowerLine, "error") || strings.HasPrefix(lowerLine, "fatal:") || strings.Contains(lowerLine, "invalid"):
+			if !strings.Contains(lowerLine, "repository contains errors") {
+				result.Errors = append(result.Errors, line)
+			}
+		}
+		if match := resticPacksReadRegex.FindStringSubmatch(line); match != nil {
+			packsRead := match[1] + "/" + match[2]
+			result.PacksRead = &packsRead
+		}
+	}
+
+	// make sure a failed check is never reported as healthy
+	if err != nil && len(result.MissingPacks) == 0 && len(result.UnreferencedBlobs) == 0 && len(result.LockErrors) == 0 && len(result.Errors) == 0 {
+		result.Errors = append(result.Errors, "restic check failed: "+err.Error())
+	}
+	return result, nil
+}
+
+// command creates a restic command for the repository with the password and environment variables of the config
+func (p ResticBackupProvider) command(dir string, args ...string) (*exec.Cmd, func(), error) {
+	cleanup := func() {}
+
+	// store password
+	passwordFile := p.config.PasswordFile
+	if p.config.Password != nil {
+		passwordFile = filepath.Join(dir, "password")
+		err := ioutil.WriteFile(passwordFile, []byte(*p.config.Password), 0600)
+		if err != nil {
+			return nil, cleanup, err
+		}
+		cleanup = func() {
+			os.Remove(passwordFile)
+		}
+	}
+
+	cmd := exec.Command("restic", append(args, "--repo", p.config.Repository, "--password-file", passwordFile)...)
+	env := os.Environ()
+	if p.config.Env != nil {
+		for key, value := range p.config.Env {
+			env = append(env, key+"="+value)
+		}
+	}
+	cmd.Env = env
+	return cmd, cleanup, nil
+}
+
+func containsAny(s string, substrings ...string) bool {
+	for _, substring := range substrings {
+		if strings.Contains(s, substring) {
+			return true
+		}
+	}
+	return false
+}
+
 func NewResticBackupProvider(config ResticConfig) ResticBackupProvider {
 	resticBackupProvider := ResticBackupProvider{
 		config: config,
@@ -0,0 +1,239 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/runtime"
+)
+
+// Port of the node in the Docker container, it is published on the host by the runtime
+const Port = "9200"
+
+const (
+	requestTimeout = 5 * time.Minute
+	maxBackoff     = 30 * time.Second
+)
+
+// Client calls the REST API of an Elasticsearch or OpenSearch node from the validator process
+type Client struct {
+	runtimeProvider runtime.RuntimeProvider
+	flavour         Flavour
+	httpClient      *http.Client
+}
+
+// APIError is returned when the node responds with an error status, the type and reason are parsed from the error body
+type APIError struct {
+	StatusCode int
+	Type       string
+	Reason     string
+	Body       string
+}
+
+type errorResponse struct {
+	Error json.RawMessage `json:"error"`
+}
+
+type errorCause struct {
+	Type      string       `json:"type"`
+	Reason    string       `json:"reason"`
+	RootCause []errorCause `json:"root_cause"`
+}
+
+func (e *APIError) Error() string {
+	if e.Type != "" {
+		return fmt.Sprintf("request failed with status %d: %s: %s", e.StatusCode, e.Type, e.Reason)
+	}
+	return fmt.Sprintf("request failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// Get requests the path and decodes the JSON response into result
+func (c Client) Get(testName string, path string, result interface{}) error {
+	return c.Request(testName, http.MethodGet, path, nil, result)
+}
+
+// Request sends the body as JSON and decodes the JSON response into result, temporary failures are retried with a backoff.
+// Requests that change the cluster (eg. a restore) are only retried when they didn't reach the node.
+func (c Client) Request(testName string, method string, path string, body interface{}, result interface{}) error {
+	var requestBody []byte
+	if body != nil {
+		var err error
+		requestBody, err = json.Marshal(body)
+		if err != nil {
+			return err
+		}
+	}
+
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		retry, err := c.do(testName, method, path, requestBody, result)
+		if err == nil || !retry || attempt >= c.flavour.Retries {
+			return err
+		}
+		log.Printf("[%s] %s %s failed: %s, retrying in %s...", testName, method, path, err, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// WaitForReady waits until the node accepts requests and the cluster has at least a yellow status
+func (c Client) WaitForReady(testName string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		_, err := c.do(testName, http.MethodGet, "/_cluster/health?wait_for_status=yellow&timeout=10s", nil, nil)
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("[%s] %s didn't become ready within %s: %s", testName, c.flavour.Name, timeout, err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// do executes a single request and returns if it may be retried when it failed
+func (c Client) do(testName string, method string, path string, requestBody []byte, result interface{}) (bool, error) {
+	baseUrl, err := c.getBaseUrl(testName)
+	if err != nil {
+		return false, err
+	}
+
+	var bodyReader io.Reader
+	if requestBody != nil {
+		bodyReader = bytes.NewReader(requestBody)
+	}
+	request, err := http.NewRequest(method, baseUrl+path, bodyReader)
+	if err != nil {
+		return false, err
+	}
+	if requestBody != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	request.Header.Set("Accept", "application/json")
+	if c.flavour.Username != nil {
+		password := ""
+		if c.flavour.Password != nil {
+			password = *c.flavour.Password
+		}
+		request.SetBasicAuth(*c.flavour.Username, password)
+	}
+
+	idempotent := method == http.MethodGet || method == http.MethodHead
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		// Connection errors are temporary while the node is starting, the request wasn't sent when the connection couldn't be made
+		return idempotent || isDialError(err), err
+	}
+	defer response.Body.Close()
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return idempotent, err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		// The node rejects requests with 429 before handling them, a proxy may have forwarded the request before failing
+		retry := response.StatusCode == http.StatusTooManyRequests || (idempotent && (response.StatusCode == http.StatusBadGateway ||
+			response.StatusCode == http.StatusServiceUnavailable || response.StatusCode == http.StatusGatewayTimeout))
+		return retry, parseAPIError(response.StatusCode, responseBody)
+	}
+	if result != nil {
+		err = json.Unmarshal(responseBody, result)
+		if err != nil {
+			return false, fmt.Errorf("invalid response of %s %s: %s", method, path, err)
+		}
+	}
+	return false, nil
+}
+
+// getBaseUrl returns the external url, or the address of the published port of the container
+func (c Client) getBaseUrl(testName string) (string, error) {
+	if c.flavour.Url != nil {
+		return strings.TrimSuffix(*c.flavour.Url, "/"), nil
+	}
+	if c.runtimeProvider == nil {
+		return "", fmt.Errorf("[%s] %s needs a 'docker' config or an external 'url'", testName, c.flavour.Name)
+	}
+	address, err := c.runtimeProvider.GetAddress(testName, Port)
+	if err != nil {
+		return "", err
+	}
+	return c.flavour.Scheme + "://" + *address, nil
+}
+
+// isDialError checks if the connection to the node couldn't be made, so the request wasn't sent
+func isDialError(err error) bool {
+	var opError *net.OpError
+	return errors.As(err, &opError) && opError.Op == "dial"
+}
+
+func parseAPIError(statusCode int, body []byte) *APIError {
+	apiError := &APIError{
+		StatusCode: statusCode,
+		Body:       strings.TrimSpace(string(body)),
+	}
+
+	// The error is an object with a root cause, or a plain message (eg. by the security plugin)
+	response := errorResponse{}
+	if json.Unmarshal(body, &response) != nil || response.Error == nil {
+		return apiError
+	}
+	cause := errorCause{}
+	if json.Unmarshal(response.Error, &cause) == nil {
+		if len(cause.RootCause) > 0 {
+			cause = cause.RootCause[0]
+		}
+		apiError.Type = cause.Type
+		apiError.Reason = cause.Reason
+		return apiError
+	}
+	var message string
+	if json.Unmarshal(response.Error, &message) == nil {
+		apiError.Reason = message
+		apiError.Type = "error"
+	}
+	return apiError
+}
+
+// NewClient creates a client for the flavour, the runtime provider is used to find the address of the container
+func NewClient(runtimeProvider runtime.RuntimeProvider, flavour Flavour) (*Client, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: flavour.Insecure,
+	}
+	if flavour.CaFile != nil {
+		ca, err := ioutil.ReadFile(*flavour.CaFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", *flavour.CaFile)
+		}
+	}
+
+	client := &Client{
+		runtimeProvider: runtimeProvider,
+		flavour:         flavour,
+		httpClient: &http.Client{
+			Timeout: requestTimeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+	}
+	return client, nil
+}
@@ -0,0 +1,193 @@
+package elasticsearch
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/elasticsearch/elasticsearchtest"
+)
+
+func newTestClient(t *testing.T, url string, retries int) *Client {
+	flavour, err := NewFlavour(Config{Url: &url, Retries: &retries}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(nil, *flavour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestClientGet(t *testing.T) {
+	server := elasticsearchtest.NewServer(t, "testdata", map[string][]elasticsearchtest.Response{
+		"GET /_cat/indices?format=json&h=index": {{Status: http.StatusOK, File: "elasticsearch/cat-indices.json"}},
+	})
+	client := newTestClient(t, server.URL, 0)
+
+	indices := []struct {
+		Index string `json:"index"`
+	}{}
+	err := client.Get("test", "/_cat/indices?format=json&h=index", &indices)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indices) != 3 || indices[0].Index != "logs-2024.01.01" || indices[2].Index != "customers" {
+		t.Errorf("unexpected indices %v", indices)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		response   elasticsearchtest.Response
+		statusCode int
+		errorType  string
+		reason     string
+		message    string
+	}{
+		{
+			name:       "elasticsearch repository missing",
+			response:   elasticsearchtest.Response{Status: http.StatusNotFound, File: "elasticsearch/error-repository-missing.json"},
+			statusCode: http.StatusNotFound,
+			errorType:  "repository_missing_exception",
+			reason:     "[backup] missing",
+			message:    "request failed with status 404: repository_missing_exception: [backup] missing",
+		},
+		{
+			name:       "elasticsearch without reason",
+			response:   elasticsearchtest.Response{Status: http.StatusServiceUnavailable, File: "elasticsearch/error-unavailable.json"},
+			statusCode: http.StatusServiceUnavailable,
+			errorType:  "master_not_discovered_exception",
+			message:    "request failed with status 503: master_not_discovered_exception: ",
+		},
+		{
+			name:       "opensearch security plugin without credentials",
+			response:   elasticsearchtest.Response{Status: http.StatusUnauthorized, File: "opensearch/error-unauthorized.txt"},
+			statusCode: http.StatusUnauthorized,
+			message:    "request failed with status 401: Unauthorized",
+		},
+		{
+			name:       "opensearch security plugin without permissions",
+			response:   elasticsearchtest.Response{Status: http.StatusForbidden, File: "opensearch/error-forbidden.json"},
+			statusCode: http.StatusForbidden,
+			errorType:  "security_exception",
+			reason:     "no permissions for [cluster:admin/snapshot/get] and User [name=reader, backend_roles=[], requestedTenant=null]",
+		},
+		{
+			name:       "opensearch plain message",
+			response:   elasticsearchtest.Response{Status: http.StatusMethodNotAllowed, File: "opensearch/error-method.json"},
+			statusCode: http.StatusMethodNotAllowed,
+			errorType:  "error",
+			reason:     "Incorrect HTTP method for uri [/_snapshot/backup/_all] and method [POST], allowed: [GET]",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := elasticsearchtest.NewServer(t, "testdata", map[string][]elasticsearchtest.Response{
+				"GET /_snapshot/backup/_all": {test.response},
+			})
+			client := newTestClient(t, server.URL, 0)
+
+			err := client.Get("test", "/_snapshot/backup/_all", nil)
+			var apiError *APIError
+			if !errors.As(err, &apiError) {
+				t.Fatalf("expected an APIError, got %v", err)
+			}
+			if apiError.StatusCode != test.statusCode {
+				t.Errorf("expected status %d, got %d", test.statusCode, apiError.StatusCode)
+			}
+			if apiError.Type != test.errorType {
+				t.Errorf("expected type %q, got %q", test.errorType, apiError.Type)
+			}
+			if test.reason != "" && apiError.Reason != test.reason {
+				t.Errorf("expected reason %q, got %q", test.reason, apiError.Reason)
+			}
+			if test.message != "" && apiError.Error() != test.message {
+				t.Errorf("expected message %q, got %q", test.message, apiError.Error())
+			}
+		})
+	}
+}
+
+func TestClientRetriesGet(t *testing.T) {
+	key := "GET /_snapshot/backup/_all"
+	server := elasticsearchtest.NewServer(t, "testdata", map[string][]elasticsearchtest.Response{
+		key: {
+			{Status: http.StatusServiceUnavailable, File: "elasticsearch/error-unavailable.json"},
+			{Status: http.StatusOK, File: "elasticsearch/snapshots.json"},
+		},
+	})
+	client := newTestClient(t, server.URL, 1)
+
+	err := client.Get("test", "/_snapshot/backup/_all", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls := server.Calls(key); calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestClientDoesNotRetryRestore(t *testing.T) {
+	key := "POST /_snapshot/backup/nightly-2024.01.02/_restore"
+	server := elasticsearchtest.NewServer(t, "testdata", map[string][]elasticsearchtest.Response{
+		key: {
+			{Status: http.StatusGatewayTimeout, File: "elasticsearch/error-unavailable.json"},
+			{Status: http.StatusInternalServerError, File: "elasticsearch/error-index-exists.json"},
+		},
+	})
+	client := newTestClient(t, server.URL, 3)
+
+	err := client.Request("test", http.MethodPost, "/_snapshot/backup/nightly-2024.01.02/_restore", map[string]string{}, nil)
+	var apiError *APIError
+	if !errors.As(err, &apiError) || apiError.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("expected the gateway timeout, got %v", err)
+	}
+	if calls := server.Calls(key); calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestClientUnreachableNode(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+	client := newTestClient(t, url, 0)
+
+	err := client.Request("test", http.MethodPost, "/_snapshot/backup/nightly-2024.01.02/_restore", map[string]string{}, nil)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !isDialError(err) {
+		t.Errorf("expected a dial error, got %v", err)
+	}
+}
+
+func TestClientOpenSearchCredentials(t *testing.T) {
+	var username, password string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ = r.BasicAuth()
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+
+	flavourName := "opensearch"
+	flavour, err := NewFlavour(Config{Flavour: &flavourName, Url: &server.URL}, []string{"OPENSEARCH_INITIAL_ADMIN_PASSWORD=Secret-123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(nil, *flavour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = client.Get("test", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if username != "admin" || password != "Secret-123" {
+		t.Errorf("expected the admin credentials, got %s:%s", username, password)
+	}
+}
@@ -0,0 +1,34 @@
+package elasticsearch
+
+// DefaultRepository is the name of the snapshot repository when none is configured
+const DefaultRepository = "backup"
+
+type Config struct {
+	Flavour      *string         `yaml:"flavour"`
+	Url          *string         `yaml:"url"`
+	Username     *string         `yaml:"username"`
+	Password     *string         `yaml:"password"`
+	PasswordFile *string         `yaml:"passwordFile"`
+	Insecure     *bool           `yaml:"insecure"`
+	CaFile       *string         `yaml:"caFile"`
+	Retries      *int            `yaml:"retries"`
+	Repository   *string         `yaml:"repository"`
+	Snapshot     *SnapshotConfig `yaml:"snapshot"`
+}
+
+type SnapshotConfig struct {
+	Name               *string `yaml:"name"`
+	Indices            *string `yaml:"indices"`
+	IncludeGlobalState *bool   `yaml:"includeGlobalState"`
+	RenamePattern      *string `yaml:"renamePattern"`
+	RenameReplacement  *string `yaml:"renameReplacement"`
+	Partial            bool    `yaml:"partial"`
+}
+
+// GetRepository returns the name of the snapshot repository
+func (c Config) GetRepository() string {
+	if c.Repository != nil {
+		return *c.Repository
+	}
+	return DefaultRepository
+}
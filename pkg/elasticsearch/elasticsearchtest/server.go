@@ -0,0 +1,87 @@
+// Package elasticsearchtest replays recorded responses of Elasticsearch and OpenSearch nodes in tests
+package elasticsearchtest
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Response is a response of a node that was recorded in a file, json files are served as application/json
+type Response struct {
+	Status int
+	File   string
+}
+
+// Server replays the recorded responses per request (eg. "GET /_cat/indices?format=json"), the last response of a request is repeated
+type Server struct {
+	*httptest.Server
+	dir       string
+	mutex     sync.Mutex
+	responses map[string][]Response
+	calls     map[string]int
+	bodies    map[string][]string
+}
+
+// NewServer starts a server that serves the responses from the files in dir, it is closed when the test finishes
+func NewServer(t *testing.T, dir string, responses map[string][]Response) *Server {
+	server := &Server{
+		dir:       dir,
+		responses: responses,
+		calls:     map[string]int{},
+		bodies:    map[string][]string{},
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		defer server.mutex.Unlock()
+
+		key := r.Method + " " + r.URL.RequestURI()
+		requestBody, _ := ioutil.ReadAll(r.Body)
+		server.bodies[key] = append(server.bodies[key], string(requestBody))
+
+		recorded, ok := server.responses[key]
+		if !ok {
+			t.Errorf("unexpected request %s", key)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		call := server.calls[key]
+		server.calls[key]++
+		if call >= len(recorded) {
+			call = len(recorded) - 1
+		}
+		body, err := ioutil.ReadFile(filepath.Join(server.dir, recorded[call].File))
+		if err != nil {
+			t.Errorf("missing recorded response: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if strings.HasSuffix(recorded[call].File, ".json") {
+			w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+		} else {
+			w.Header().Set("Content-Type", "text/plain; charset=UTF-8")
+		}
+		w.WriteHeader(recorded[call].Status)
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Calls returns how often the request was received
+func (s *Server) Calls(key string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.calls[key]
+}
+
+// Bodies returns the bodies of the received requests
+func (s *Server) Bodies(key string) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.bodies[key]...)
+}
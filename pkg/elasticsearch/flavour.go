@@ -0,0 +1,117 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// Flavour contains the differences between Elasticsearch and OpenSearch nodes and how to connect to them
+type Flavour struct {
+	Name     string
+	HomeDir  string
+	Scheme   string
+	Url      *string
+	Username *string
+	Password *string
+	Insecure bool
+	CaFile   *string
+	Retries  int
+
+	// Indices of plugins that are created by the node itself and aren't part of a snapshot
+	SystemIndexPrefixes []string
+}
+
+// NewFlavour creates the flavour of the config, the environment of the Docker container is used to detect the OpenSearch security plugin settings
+func NewFlavour(config Config, environment []string) (*Flavour, error) {
+	name := "elasticsearch"
+	if config.Flavour != nil {
+		name = *config.Flavour
+	}
+
+	var flavour *Flavour
+	switch name {
+	case "elasticsearch":
+		flavour = &Flavour{
+			Name:    "elasticsearch",
+			HomeDir: "/usr/share/elasticsearch",
+			Scheme:  "http",
+		}
+	case "opensearch":
+		flavour = &Flavour{
+			Name:                "opensearch",
+			HomeDir:             "/usr/share/opensearch",
+			SystemIndexPrefixes: []string{".opendistro", ".opensearch", ".plugins-", ".ql-datasources", "security-auditlog-"},
+		}
+		if getEnv(environment, "DISABLE_SECURITY_PLUGIN") == "true" {
+			flavour.Scheme = "http"
+		} else {
+			// The demo configuration of the security plugin uses TLS with self signed certificates and the admin user
+			flavour.Scheme = "https"
+			flavour.Insecure = true
+			username := "admin"
+			password := "admin"
+			if initialPassword := getEnv(environment, "OPENSEARCH_INITIAL_ADMIN_PASSWORD"); initialPassword != "" {
+				password = initialPassword
+			}
+			flavour.Username = &username
+			flavour.Password = &password
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported elasticsearch flavour '%s', should be one of: \"elasticsearch\" or \"opensearch\"", name)
+	}
+
+	flavour.Url = config.Url
+	flavour.CaFile = config.CaFile
+	flavour.Retries = 5
+	if config.Retries != nil {
+		flavour.Retries = *config.Retries
+	}
+	if config.Username != nil {
+		flavour.Username = config.Username
+	}
+	if config.Password != nil {
+		flavour.Password = config.Password
+	} else if config.PasswordFile != nil {
+		bytes, err := ioutil.ReadFile(*config.PasswordFile)
+		if err != nil {
+			return nil, err
+		}
+		password := strings.TrimSpace(string(bytes))
+		flavour.Password = &password
+	}
+	if config.Insecure != nil {
+		flavour.Insecure = *config.Insecure
+	}
+	return flavour, nil
+}
+
+// KeystoreCommand returns the path of the keystore CLI
+func (f Flavour) KeystoreCommand() string {
+	return f.HomeDir + "/bin/" + f.Name + "-keystore"
+}
+
+// KeystoreFile returns the path of the keystore
+func (f Flavour) KeystoreFile() string {
+	return f.HomeDir + "/config/" + f.Name + ".keystore"
+}
+
+// IsSystemIndex returns true for indices that are created by the node itself
+func (f Flavour) IsSystemIndex(index string) bool {
+	for _, prefix := range f.SystemIndexPrefixes {
+		if strings.HasPrefix(index, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func getEnv(environment []string, key string) string {
+	for _, env := range environment {
+		parts := strings.SplitN(env, "=", 2)
+		if len(parts) == 2 && parts[0] == key {
+			return parts[1]
+		}
+	}
+	return ""
+}
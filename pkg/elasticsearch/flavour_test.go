@@ -0,0 +1,78 @@
+package elasticsearch
+
+import "testing"
+
+func TestNewFlavour(t *testing.T) {
+	opensearch := "opensearch"
+	tests := []struct {
+		name        string
+		config      Config
+		environment []string
+		scheme      string
+		username    string
+	}{
+		{
+			name:   "elasticsearch",
+			config: Config{},
+			scheme: "http",
+		},
+		{
+			name:     "opensearch with security plugin",
+			config:   Config{Flavour: &opensearch},
+			scheme:   "https",
+			username: "admin",
+		},
+		{
+			name:        "opensearch without security plugin",
+			config:      Config{Flavour: &opensearch},
+			environment: []string{"DISABLE_SECURITY_PLUGIN=true"},
+			scheme:      "http",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			flavour, err := NewFlavour(test.config, test.environment)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if flavour.Scheme != test.scheme {
+				t.Errorf("expected scheme %s, got %s", test.scheme, flavour.Scheme)
+			}
+			username := ""
+			if flavour.Username != nil {
+				username = *flavour.Username
+			}
+			if username != test.username {
+				t.Errorf("expected username %q, got %q", test.username, username)
+			}
+			if flavour.Retries != 5 {
+				t.Errorf("expected 5 retries, got %d", flavour.Retries)
+			}
+		})
+	}
+
+	unknown := "solr"
+	_, err := NewFlavour(Config{Flavour: &unknown}, nil)
+	if err == nil {
+		t.Error("expected an error for an unknown flavour")
+	}
+}
+
+func TestIsSystemIndex(t *testing.T) {
+	opensearch := "opensearch"
+	flavour, err := NewFlavour(Config{Flavour: &opensearch}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index, systemIndex := range map[string]bool{
+		".opendistro_security":         true,
+		".plugins-ml-config":           true,
+		"security-auditlog-2024.01.02": true,
+		"customers":                    false,
+		".kibana_1":                    false,
+	} {
+		if flavour.IsSystemIndex(index) != systemIndex {
+			t.Errorf("expected IsSystemIndex(%s) to be %t", index, systemIndex)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/runtime"
+)
+
+const clickhouseBackupDir = "/var/lib/clickhouse/backup"
+
+type ClickhouseFormatProvider struct {
+	runtimeProvider runtime.RuntimeProvider
+	config          ClickhouseConfig
+}
+
+func (p ClickhouseFormatProvider) Setup(testName string, dir string) error {
+	return p.runtimeProvider.Setup(testName, dir)
+}
+
+func (p ClickhouseFormatProvider) Destroy(testName string, dir string) error {
+	return p.runtimeProvider.Destroy(testName, dir)
+}
+
+func (p ClickhouseFormatProvider) GetRuntimeProvider() runtime.RuntimeProvider {
+	return p.runtimeProvider
+}
+
+// ImportData restores a clickhouse-backup directory, or a native backup with RESTORE ALL FROM
+func (p ClickhouseFormatProvider) ImportData(testName string, dir string, options []string) error {
+	if len(options) == 0 || strings.HasPrefix(options[len(options)-1], "-") {
+		return fmt.Errorf("[%s] the last import option should be the restored backup", testName)
+	}
+	backup := options[len(options)-1]
+
+	backupType := "clickhouse-backup"
+	if p.config.BackupType != nil {
+		backupType = *p.config.BackupType
+	}
+
+	switch backupType {
+	case "clickhouse-backup":
+		// clickhouse-backup restores backups from its local backup dir
+		backupName := path.Base(strings.TrimSuffix(backup, "/"))
+		log.Printf("[%s] Restore %s with clickhouse-backup", testName, backupName)
+		_, err := p.runtimeProvider.ExecRoot(testName, "sh", "-c", "mkdir -p \"$2\" && rm -rf \"$2/$3\" && cp -r \"$1\" \"$2/$3\" && chown -R clickhouse:clickhouse \"$2\"", "sh", backup, clickhouseBackupDir, backupName)
+		if err != nil {
+			return err
+		}
+		args := append([]string{"restore"}, options[:len(options)-1]...)
+		_, err = p.runtimeProvider.Exec(testName, "clickhouse-backup", append(args, backupName)...)
+		return err
+	case "native":
+		// eg. Disk('backups', '2021-01-01.zip')
+		log.Printf("[%s] Restore %s", testName, backup)
+		_, err := p.clickhouseClient(testName, "RESTORE ALL FROM "+backup+" SETTINGS allow_non_empty_tables=true")
+		return err
+	}
+	return fmt.Errorf("[%s] Unsupported clickhouse backup type '%s', should be one of: \"clickhouse-backup\" or \"native\"", testName, backupType)
+}
+
+func (p ClickhouseFormatProvider) ListDatabases(testName string) ([]string, error) {
+	output, err := p.clickhouseClient(testName, "SELECT name FROM system.databases WHERE name NOT IN ('system', 'INFORMATION_SCHEMA', 'information_schema')")
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(*output), nil
+}
+
+func (p ClickhouseFormatProvider) GetDatabaseSize(testName string, database string) (*uint64, error) {
+	output, err := p.clickhouseClient(testName, "SELECT sum(bytes_on_disk) FROM system.parts WHERE active AND database = "+quoteClickhouseString(database))
+	if err != nil {
+		return nil, err
+	}
+	size, err := strconv.ParseUint(strings.TrimSpace(*output), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &size, nil
+}
+
+func (p ClickhouseFormatProvider) ListTables(testName string, database string) ([]string, error) {
+	output, err := p.clickhouseClient(testName, "SELECT name FROM system.tables WHERE database = "+quoteClickhouseString(database))
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(*output), nil
+}
+
+func (p ClickhouseFormatProvider) QueryRecord(testName string, database string, query string) (map[string]interface{}, error) {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	output, err := p.clickhouseClient(testName, query+" FORMAT JSONEachRow", "--database="+database)
+	if err != nil {
+		return nil, err
+	}
+	lines := splitLines(*output)
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("[%s] query returned no records", testName)
+	}
+
+	result := map[string]interface{}{}
+	err = json.Unmarshal([]byte(lines[0]), &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (p ClickhouseFormatProvider) CountRecords(testName string, database string, table string, estimate bool) (*uint64, error) {
+	if table == "" {
+		return nil, fmt.Errorf("[%s] Counting records of the clickhouse format needs a table, set 'tables' in the rowCount assert", testName)
+	}
+	query := "SELECT count() FROM " + quoteClickhouseIdentifier(database) + "." + quoteClickhouseIdentifier(table)
+	if estimate {
+		// Use the row count of the active parts, which avoids reading the table
+		query = "SELECT sum(rows) FROM system.parts WHERE active AND database = " + quoteClickhouseString(database) + " AND table = " + quoteClickhouseString(table)
+	}
+	output, err := p.clickhouseClient(testName, query)
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.ParseUint(strings.TrimSpace(*output), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &count, nil
+}
+
+func (p ClickhouseFormatProvider) clickhouseClient(testName string, query string, args ...string) (*string, error) {
+	return p.runtimeProvider.Exec(testName, "clickhouse-client", append(args, "--query="+query)...)
+}
+
+func quoteClickhouseString(value string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(value, "\\", "\\\\"), "'", "\\'") + "'"
+}
+
+func quoteClickhouseIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+}
+
+func splitLines(output string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func NewClickhouseFormatProvider(runtimeProvider runtime.RuntimeProvider, config ClickhouseConfig) ClickhouseFormatProvider {
+	clickhouseFormatProvider := ClickhouseFormatProvider{
+		runtimeProvider: runtimeProvider,
+		config:          config,
+	}
+	return clickhouseFormatProvider
+}
@@ -0,0 +1,53 @@
+package format
+
+type PostgresqlConfig struct {
+	DumpType    *string                   `yaml:"dumpType"`
+	OnErrorStop *bool                     `yaml:"onErrorStop"`
+	Physical    *PostgresqlPhysicalConfig `yaml:"physical"`
+}
+
+type PostgresqlPhysicalConfig struct {
+	Tool            string  `yaml:"tool"`
+	DataDir         *string `yaml:"dataDir"`
+	User            *string `yaml:"user"`
+	Stanza          *string `yaml:"stanza"`
+	Backup          *string `yaml:"backup"`
+	TargetTime      *string `yaml:"targetTime"`
+	TargetLsn       *string `yaml:"targetLsn"`
+	RecoveryTimeout *string `yaml:"recoveryTimeout"`
+}
+
+type MongoConfig struct {
+	Shell                  *string  `yaml:"shell"`
+	Username               *string  `yaml:"username"`
+	Password               *string  `yaml:"password"`
+	PasswordFile           *string  `yaml:"passwordFile"`
+	AuthenticationDatabase *string  `yaml:"authenticationDatabase"`
+	OplogReplay            bool     `yaml:"oplogReplay"`
+	NsInclude              []string `yaml:"nsInclude"`
+	NsFrom                 []string `yaml:"nsFrom"`
+	NsTo                   []string `yaml:"nsTo"`
+	SchemaSampleSize       *int     `yaml:"schemaSampleSize"`
+}
+
+type SqliteConfig struct {
+	Databases []string `yaml:"databases"`
+}
+
+type EtcdConfig struct {
+	PrefixDepth *int `yaml:"prefixDepth"`
+}
+
+type VaultConfig struct {
+	UnsealKeys []VaultValue `yaml:"unsealKeys"`
+	Token      *VaultValue  `yaml:"token"`
+}
+
+type VaultValue struct {
+	Value    *string `yaml:"value"`
+	FromFile *string `yaml:"fromFile"`
+}
+
+type ClickhouseConfig struct {
+	BackupType *string `yaml:"backupType"`
+}
@@ -1,15 +1,19 @@
 package format
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
-	"strings"
+	"time"
 
+	"github.com/MaxxtonGroup/backup-validator/pkg/elasticsearch"
 	"github.com/MaxxtonGroup/backup-validator/pkg/runtime"
 )
 
@@ -24,15 +28,39 @@ type ElasticsearchKeystoreValue struct {
 	FromFile *string `yaml:"fromFile"`
 }
 
+const elasticsearchReadyTimeout = 5 * time.Minute
+
 type ElasticsearchFormatProvider struct {
 	runtimeProvider runtime.RuntimeProvider
 	repository      ElasticsearchSnapshotRepository
+	repositoryName  string
+	flavour         elasticsearch.Flavour
+	client          *elasticsearch.Client
+	state           *elasticsearchState
+}
+
+type elasticsearchState struct {
+	restoredIndices []string
 }
 
 type ElasticsearchQueryResult struct {
 	Hits *ElasticsearchQueryHit `json:"hits"`
 }
 
+type ElasticsearchCatIndex struct {
+	Index     string `json:"index"`
+	Health    string `json:"health"`
+	StoreSize string `json:"store.size"`
+}
+
+type ElasticsearchIndexMapping struct {
+	Mappings map[string]interface{} `json:"mappings"`
+}
+
+type ElasticsearchCountResult struct {
+	Count uint64 `json:"count"`
+}
+
 type ElasticsearchQueryHit struct {
 	Hits []*ElasticsearchQueryDocument `json:"hits"`
 }
@@ -42,16 +70,26 @@ type ElasticsearchQueryDocument struct {
 }
 
 func (p ElasticsearchFormatProvider) Setup(testName string, dir string) error {
-	err := p.runtimeProvider.Setup(testName, dir)
+	if p.runtimeProvider != nil {
+		err := p.runtimeProvider.Setup(testName, dir)
+		if err != nil {
+			return err
+		}
+	}
+	err := p.client.WaitForReady(testName, elasticsearchReadyTimeout)
 	if err != nil {
 		return err
 	}
 
 	// Configure keystore of elasticsearch node
 	if p.repository.Keystore != nil {
+		if p.runtimeProvider == nil {
+			return fmt.Errorf("[%s] the keystore can only be configured in a Docker container", testName)
+		}
+
 		// create keystore
 		log.Printf("[%s] Create Keystore", testName)
-		_, err = p.runtimeProvider.Exec(testName, "bash", "-c", "if [[ ! -f /usr/share/elasticsearch/config/elasticsearch.keystore ]] ; then /usr/share/elasticsearch/bin/elasticsearch-keystore create; else true; fi")
+		_, err = p.runtimeProvider.Exec(testName, "bash", "-c", "if [[ ! -f "+p.flavour.KeystoreFile()+" ]] ; then "+p.flavour.KeystoreCommand()+" create; else true; fi")
 		if err != nil {
 			return err
 		}
@@ -94,7 +132,7 @@ func (p ElasticsearchFormatProvider) Setup(testName string, dir string) error {
 
 			// Store value in keystore
 			log.Printf("[%s] Store %s in keystore", testName, key)
-			_, err = p.runtimeProvider.Exec(testName, "bash", "-c", "ls -la /mnt/host/"+filepath.Base(keyFile.Name())+" && /usr/share/elasticsearch/bin/elasticsearch-keystore add-file -f "+key+" /mnt/host/"+filepath.Base(keyFile.Name()))
+			_, err = p.runtimeProvider.Exec(testName, "bash", "-c", "ls -la /mnt/host/"+filepath.Base(keyFile.Name())+" && "+p.flavour.KeystoreCommand()+" add-file -f "+key+" /mnt/host/"+filepath.Base(keyFile.Name()))
 			if err != nil {
 				return err
 			}
@@ -102,7 +140,7 @@ func (p ElasticsearchFormatProvider) Setup(testName string, dir string) error {
 
 		// Reload keystore
 		log.Printf("[%s] Reload keystore", testName)
-		_, err = p.runtimeProvider.Exec(testName, "curl", "--fail", "-X", "POST", "http://localhost:9200/_nodes/reload_secure_settings?pretty", "-H", "Content-Type: application/json", "-d", "{}")
+		err = p.client.Request(testName, http.MethodPost, "/_nodes/reload_secure_settings", map[string]interface{}{}, nil)
 		if err != nil {
 			return err
 		}
@@ -110,24 +148,38 @@ func (p ElasticsearchFormatProvider) Setup(testName string, dir string) error {
 	}
 
 	// Configure snapshot repository
-	bytes, err := json.Marshal(p.repository)
-	if err != nil {
-		return err
-	}
 	log.Printf("[%s] Configure snapshot repository", testName)
-	output, err := p.runtimeProvider.Exec(testName, "curl", "--output", "/dev/stdout", "--write-out", "%{http_code}", "-X", "PUT", "http://localhost:9200/_snapshot/backup", "-H", "Content-Type: application/json", "-d", string(bytes))
-	if err != nil {
-		return err
+	return p.client.Request(testName, http.MethodPut, "/_snapshot/"+url.PathEscape(p.repositoryName), p.repository, nil)
+}
+
+// Destroy removes the container, on an external cluster the restored indices are deleted, otherwise the restore of the next run fails because the indices already exist
+func (p ElasticsearchFormatProvider) Destroy(testName string, dir string) error {
+	if p.flavour.Url != nil {
+		for _, index := range p.state.restoredIndices {
+			log.Printf("[%s] Delete restored index %s", testName, index)
+			err := p.client.Request(testName, http.MethodDelete, "/"+url.PathEscape(index), nil, nil)
+			var apiError *elasticsearch.APIError
+			if errors.As(err, &apiError) && apiError.StatusCode == http.StatusNotFound {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
 	}
-	if !strings.HasSuffix(*output, "200") {
-		return fmt.Errorf("the requested URL returned error: %s", *output)
+	if p.runtimeProvider == nil {
+		return nil
 	}
+	return p.runtimeProvider.Destroy(testName, dir)
+}
 
-	return nil
+// SetRestoredDatabases keeps the indices restored by the backup provider, so they can be deleted again
+func (p ElasticsearchFormatProvider) SetRestoredDatabases(testName string, databases []string) {
+	p.state.restoredIndices = databases
 }
 
-func (p ElasticsearchFormatProvider) Destroy(testName string, dir string) error {
-	return p.runtimeProvider.Destroy(testName, dir)
+func (p ElasticsearchFormatProvider) GetRuntimeProvider() runtime.RuntimeProvider {
+	return p.runtimeProvider
 }
 
 func (p ElasticsearchFormatProvider) ImportData(testName string, dir string, options []string) error {
@@ -136,43 +188,109 @@ func (p ElasticsearchFormatProvider) ImportData(testName string, dir string, opt
 }
 
 func (p ElasticsearchFormatProvider) ListDatabases(testName string) ([]string, error) {
-	output, err := p.runtimeProvider.Exec(testName, "curl", "--fail", "-X", "GET", "http://localhost:9200/_cat/indices?h=index")
+	indices := []ElasticsearchCatIndex{}
+	err := p.client.Get(testName, "/_cat/indices?format=json&h=index", &indices)
 	if err != nil {
 		return nil, err
 	}
 
-	return strings.Split(*output, "\n"), nil
+	databaseNames := []string{}
+	for _, index := range indices {
+		if !p.flavour.IsSystemIndex(index.Index) {
+			databaseNames = append(databaseNames, index.Index)
+		}
+	}
+	return databaseNames, nil
 }
 
+// ListTables returns the field paths in the mapping of the index, eg. user.name
 func (p ElasticsearchFormatProvider) ListTables(testName string, database string) ([]string, error) {
-	output, err := p.runtimeProvider.Exec(testName, "curl", "--fail", "-X", "GET", "http://localhost:9200/"+database+"/_search?size=1")
+	mappings, err := p.GetMappings(testName, database)
 	if err != nil {
 		return nil, err
 	}
 
-	result := ElasticsearchQueryResult{}
-	err = json.Unmarshal([]byte(*output), &result)
+	fields := map[string]bool{}
+	for _, mapping := range mappings {
+		for field := range mapping {
+			fields[field] = true
+		}
+	}
+	fieldNames := []string{}
+	for field := range fields {
+		fieldNames = append(fieldNames, field)
+	}
+	sort.Strings(fieldNames)
+	return fieldNames, nil
+}
+
+// GetMappings returns the type of every field path per index, multi-fields are included (eg. name.keyword)
+func (p ElasticsearchFormatProvider) GetMappings(testName string, index string) (map[string]map[string]string, error) {
+	result := map[string]ElasticsearchIndexMapping{}
+	err := p.client.Get(testName, "/"+url.PathEscape(index)+"/_mapping", &result)
 	if err != nil {
 		return nil, err
 	}
 
-	fields := []string{}
-	if len(result.Hits.Hits) > 0 {
-		for key := range result.Hits.Hits[0].Source {
-			fields = append(fields, key)
+	mappings := map[string]map[string]string{}
+	for indexName, indexMapping := range result {
+		properties, ok := indexMapping.Mappings["properties"].(map[string]interface{})
+		if !ok && len(indexMapping.Mappings) == 1 {
+			// Elasticsearch 6 and older have a mapping type level, eg. _doc
+			for _, typeMapping := range indexMapping.Mappings {
+				if typeMapping, ok := typeMapping.(map[string]interface{}); ok {
+					properties, _ = typeMapping["properties"].(map[string]interface{})
+				}
+			}
 		}
+		fields := map[string]string{}
+		flattenMappingProperties("", properties, fields)
+		mappings[indexName] = fields
+	}
+	return mappings, nil
+}
+
+// GetSchema returns the field paths and their type in the mapping of the index
+func (p ElasticsearchFormatProvider) GetSchema(testName string, database string) (DatabaseSchema, error) {
+	mappings, err := p.GetMappings(testName, database)
+	if err != nil {
+		return nil, err
 	}
-	return fields, nil
+	fields, ok := mappings[database]
+	if !ok {
+		fields = map[string]string{}
+	}
+	return DatabaseSchema{"mappings": fields}, nil
+}
+
+// GetIndexHealth returns the health of every index, eg. green
+func (p ElasticsearchFormatProvider) GetIndexHealth(testName string) (map[string]string, error) {
+	indices := []ElasticsearchCatIndex{}
+	err := p.client.Get(testName, "/_cat/indices?format=json&h=index,health", &indices)
+	if err != nil {
+		return nil, err
+	}
+
+	health := map[string]string{}
+	for _, index := range indices {
+		if !p.flavour.IsSystemIndex(index.Index) {
+			health[index.Index] = index.Health
+		}
+	}
+	return health, nil
 }
 
 func (p ElasticsearchFormatProvider) GetDatabaseSize(testName string, database string) (*uint64, error) {
-	output, err := p.runtimeProvider.Exec(testName, "curl", "--fail", "-X", "GET", "http://localhost:9200/_cat/indices/"+database+"?h=store.size&bytes=b")
+	indices := []ElasticsearchCatIndex{}
+	err := p.client.Get(testName, "/_cat/indices/"+url.PathEscape(database)+"?format=json&h=store.size&bytes=b", &indices)
 	if err != nil {
 		return nil, err
 	}
+	if len(indices) == 0 {
+		return nil, fmt.Errorf("[%s] index %s not found", testName, database)
+	}
 
-	sizeString := strings.TrimSpace(*output)
-	size, err := strconv.ParseUint(sizeString, 10, 64)
+	size, err := strconv.ParseUint(indices[0].StoreSize, 10, 64)
 	if err != nil {
 		return nil, err
 	}
@@ -183,10 +301,47 @@ func (p ElasticsearchFormatProvider) QueryRecord(testName string, database strin
 	return nil, fmt.Errorf("[%s] QueryRecord not supported for postgresql yet", testName)
 }
 
-func NewElasticsearchFormatProvider(runtimeProvider runtime.RuntimeProvider, elasticsearchSnapshotRepository ElasticsearchSnapshotRepository) ElasticsearchFormatProvider {
+// CountRecords counts the documents in an index, indices don't have tables so the table is ignored
+func (p ElasticsearchFormatProvider) CountRecords(testName string, database string, table string, estimate bool) (*uint64, error) {
+	result := ElasticsearchCountResult{}
+	err := p.client.Get(testName, "/"+url.PathEscape(database)+"/_count", &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result.Count, nil
+}
+
+func flattenMappingProperties(prefix string, properties map[string]interface{}, fields map[string]string) {
+	for name, value := range properties {
+		property, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path := prefix + name
+
+		// Object fields don't have a type, but have properties
+		fieldType, _ := property["type"].(string)
+		if fieldType == "" {
+			fieldType = "object"
+		}
+		fields[path] = fieldType
+		if subProperties, ok := property["properties"].(map[string]interface{}); ok {
+			flattenMappingProperties(path+".", subProperties, fields)
+		}
+		if multiFields, ok := property["fields"].(map[string]interface{}); ok {
+			flattenMappingProperties(path+".", multiFields, fields)
+		}
+	}
+}
+
+func NewElasticsearchFormatProvider(runtimeProvider runtime.RuntimeProvider, elasticsearchSnapshotRepository ElasticsearchSnapshotRepository, repositoryName string, flavour elasticsearch.Flavour, client *elasticsearch.Client) ElasticsearchFormatProvider {
 	elasticsarchFormatProvider := ElasticsearchFormatProvider{
 		runtimeProvider: runtimeProvider,
 		repository:      elasticsearchSnapshotRepository,
+		repositoryName:  repositoryName,
+		flavour:         flavour,
+		client:          client,
+		state:           &elasticsearchState{},
 	}
 	return elasticsarchFormatProvider
 }
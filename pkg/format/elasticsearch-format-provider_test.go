@@ -0,0 +1,190 @@
+package format
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/elasticsearch"
+	"github.com/MaxxtonGroup/backup-validator/pkg/elasticsearch/elasticsearchtest"
+)
+
+const recordedResponses = "../elasticsearch/testdata"
+
+func newTestElasticsearchFormatProvider(t *testing.T, url string, flavourName string) ElasticsearchFormatProvider {
+	retries := 0
+	flavour, err := elasticsearch.NewFlavour(elasticsearch.Config{Flavour: &flavourName, Url: &url, Retries: &retries}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := elasticsearch.NewClient(nil, *flavour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewElasticsearchFormatProvider(nil, ElasticsearchSnapshotRepository{}, elasticsearch.DefaultRepository, *flavour, client)
+}
+
+func TestElasticsearchListDatabases(t *testing.T) {
+	tests := []struct {
+		flavour  string
+		expected []string
+	}{
+		{
+			flavour:  "elasticsearch",
+			expected: []string{"logs-2024.01.01", "logs-2024.01.02", "customers"},
+		},
+		{
+			// The indices of the security and other plugins aren't part of the snapshot
+			flavour:  "opensearch",
+			expected: []string{"logs-2024.01.02", "customers"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.flavour, func(t *testing.T) {
+			server := elasticsearchtest.NewServer(t, recordedResponses, map[string][]elasticsearchtest.Response{
+				"GET /_cat/indices?format=json&h=index": {{Status: http.StatusOK, File: test.flavour + "/cat-indices.json"}},
+			})
+			provider := newTestElasticsearchFormatProvider(t, server.URL, test.flavour)
+
+			databases, err := provider.ListDatabases("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(databases, test.expected) {
+				t.Errorf("expected indices %v, got %v", test.expected, databases)
+			}
+		})
+	}
+}
+
+func TestElasticsearchGetIndexHealth(t *testing.T) {
+	tests := []struct {
+		flavour  string
+		expected map[string]string
+	}{
+		{
+			flavour:  "elasticsearch",
+			expected: map[string]string{"logs-2024.01.01": "yellow", "logs-2024.01.02": "green", "customers": "red"},
+		},
+		{
+			flavour:  "opensearch",
+			expected: map[string]string{"logs-2024.01.02": "yellow", "customers": "green"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.flavour, func(t *testing.T) {
+			server := elasticsearchtest.NewServer(t, recordedResponses, map[string][]elasticsearchtest.Response{
+				"GET /_cat/indices?format=json&h=index,health": {{Status: http.StatusOK, File: test.flavour + "/cat-indices-health.json"}},
+			})
+			provider := newTestElasticsearchFormatProvider(t, server.URL, test.flavour)
+
+			health, err := provider.GetIndexHealth("test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(health, test.expected) {
+				t.Errorf("expected health %v, got %v", test.expected, health)
+			}
+		})
+	}
+}
+
+func TestElasticsearchGetDatabaseSize(t *testing.T) {
+	server := elasticsearchtest.NewServer(t, recordedResponses, map[string][]elasticsearchtest.Response{
+		"GET /_cat/indices/customers?format=json&h=store.size&bytes=b": {{Status: http.StatusOK, File: "elasticsearch/cat-indices-size.json"}},
+		"GET /_cat/indices/orders?format=json&h=store.size&bytes=b":    {{Status: http.StatusNotFound, File: "elasticsearch/error-index-not-found.json"}},
+	})
+	provider := newTestElasticsearchFormatProvider(t, server.URL, "elasticsearch")
+
+	size, err := provider.GetDatabaseSize("test", "customers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *size != 52814 {
+		t.Errorf("expected size 52814, got %d", *size)
+	}
+
+	_, err = provider.GetDatabaseSize("test", "orders")
+	if err == nil || !strings.Contains(err.Error(), "index_not_found_exception: no such index [orders]") {
+		t.Errorf("expected index not found, got %v", err)
+	}
+}
+
+func TestElasticsearchGetMappings(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		expected map[string]string
+	}{
+		{
+			name: "elasticsearch 7",
+			file: "elasticsearch/mapping.json",
+			expected: map[string]string{
+				"name":         "text",
+				"name.keyword": "keyword",
+				"address":      "object",
+				"address.city": "keyword",
+				"created":      "date",
+			},
+		},
+		{
+			name: "elasticsearch 6 mapping type",
+			file: "elasticsearch/mapping-6.json",
+			expected: map[string]string{
+				"name":    "text",
+				"created": "date",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := elasticsearchtest.NewServer(t, recordedResponses, map[string][]elasticsearchtest.Response{
+				"GET /customers/_mapping": {{Status: http.StatusOK, File: test.file}},
+			})
+			provider := newTestElasticsearchFormatProvider(t, server.URL, "elasticsearch")
+
+			mappings, err := provider.GetMappings("test", "customers")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(mappings["customers"], test.expected) {
+				t.Errorf("expected fields %v, got %v", test.expected, mappings["customers"])
+			}
+		})
+	}
+}
+
+func TestElasticsearchCountRecords(t *testing.T) {
+	server := elasticsearchtest.NewServer(t, recordedResponses, map[string][]elasticsearchtest.Response{
+		"GET /customers/_count": {{Status: http.StatusOK, File: "elasticsearch/count.json"}},
+	})
+	provider := newTestElasticsearchFormatProvider(t, server.URL, "elasticsearch")
+
+	count, err := provider.CountRecords("test", "customers", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *count != 1284 {
+		t.Errorf("expected 1284 documents, got %d", *count)
+	}
+}
+
+func TestElasticsearchDestroyDeletesRestoredIndices(t *testing.T) {
+	server := elasticsearchtest.NewServer(t, recordedResponses, map[string][]elasticsearchtest.Response{
+		"DELETE /restored-customers": {{Status: http.StatusOK, File: "elasticsearch/delete-index.json"}},
+		"DELETE /restored-orders":    {{Status: http.StatusNotFound, File: "elasticsearch/error-index-not-found.json"}},
+	})
+	provider := newTestElasticsearchFormatProvider(t, server.URL, "elasticsearch")
+
+	provider.SetRestoredDatabases("test", []string{"restored-customers", "restored-orders"})
+	err := provider.Destroy("test", t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"DELETE /restored-customers", "DELETE /restored-orders"} {
+		if calls := server.Calls(key); calls != 1 {
+			t.Errorf("expected 1 call of %s, got %d", key, calls)
+		}
+	}
+}
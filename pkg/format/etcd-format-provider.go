@@ -0,0 +1,223 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/runtime"
+)
+
+const (
+	etcdRestoreEndpoint = "http://127.0.0.1:23790"
+	etcdRestorePeer     = "http://127.0.0.1:23800"
+	etcdRestoreDir      = "/tmp/etcd-restore"
+	etcdRestoreTimeout  = 5 * time.Minute
+)
+
+type EtcdFormatProvider struct {
+	runtimeProvider runtime.RuntimeProvider
+	config          EtcdConfig
+	state           *etcdState
+}
+
+type etcdState struct {
+	recoveryPoint *RecoveryPoint
+}
+
+type EtcdSnapshotStatus struct {
+	Hash      uint64 `json:"hash"`
+	Revision  int64  `json:"revision"`
+	TotalKey  int64  `json:"totalKey"`
+	TotalSize int64  `json:"totalSize"`
+}
+
+type EtcdEndpointStatus struct {
+	Status struct {
+		Header struct {
+			Revision int64 `json:"revision"`
+		} `json:"header"`
+		DbSize int64 `json:"dbSize"`
+	} `json:"Status"`
+}
+
+type EtcdCountResult struct {
+	Count uint64 `json:"count"`
+}
+
+func (p EtcdFormatProvider) Setup(testName string, dir string) error {
+	return p.runtimeProvider.Setup(testName, dir)
+}
+
+func (p EtcdFormatProvider) Destroy(testName string, dir string) error {
+	return p.runtimeProvider.Destroy(testName, dir)
+}
+
+func (p EtcdFormatProvider) GetRuntimeProvider() runtime.RuntimeProvider {
+	return p.runtimeProvider
+}
+
+// ImportData restores the snapshot with etcdutl into a new data dir, starts a separate etcd on it and checks the revision
+func (p EtcdFormatProvider) ImportData(testName string, dir string, options []string) error {
+	if len(options) == 0 || strings.HasPrefix(options[len(options)-1], "-") {
+		return fmt.Errorf("[%s] the last import option should be the restored etcd snapshot file", testName)
+	}
+	file := options[len(options)-1]
+
+	// Read hash and revision of the snapshot
+	output, err := p.runtimeProvider.Exec(testName, "etcdutl", "snapshot", "status", file, "--write-out=json")
+	if err != nil {
+		return err
+	}
+	snapshotStatus := EtcdSnapshotStatus{}
+	err = json.Unmarshal([]byte(*output), &snapshotStatus)
+	if err != nil {
+		return err
+	}
+	log.Printf("[%s] Snapshot %s has hash %x, revision %d and %d keys", testName, file, snapshotStatus.Hash, snapshotStatus.Revision, snapshotStatus.TotalKey)
+
+	// Restore snapshot, etcdutl verifies the hash of the snapshot
+	args := []string{"snapshot", "restore", file, "--data-dir=" + etcdRestoreDir, "--name=default",
+		"--initial-cluster=default=" + etcdRestorePeer, "--initial-advertise-peer-urls=" + etcdRestorePeer}
+	args = append(args, options[:len(options)-1]...)
+	_, err = p.runtimeProvider.Exec(testName, "sh", "-c", "rm -rf \""+etcdRestoreDir+"\"")
+	if err != nil {
+		return err
+	}
+	log.Printf("[%s] Restore snapshot %s", testName, file)
+	_, err = p.runtimeProvider.Exec(testName, "etcdutl", args...)
+	if err != nil {
+		return err
+	}
+
+	// Start etcd
+	_, err = p.runtimeProvider.Exec(testName, "sh", "-c", "nohup etcd --name=default --data-dir=\""+etcdRestoreDir+"\" --listen-client-urls="+etcdRestoreEndpoint+
+		" --advertise-client-urls="+etcdRestoreEndpoint+" --listen-peer-urls="+etcdRestorePeer+" > \""+etcdRestoreDir+".log\" 2>&1 &")
+	if err != nil {
+		return err
+	}
+
+	// Wait for etcd to become available
+	deadline := time.Now().Add(etcdRestoreTimeout)
+	var endpointStatus []EtcdEndpointStatus
+	for {
+		output, err := p.etcdctl(testName, "endpoint", "status", "--write-out=json")
+		if err == nil {
+			err = json.Unmarshal([]byte(*output), &endpointStatus)
+			if err == nil && len(endpointStatus) > 0 {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			logs, _ := p.runtimeProvider.Exec(testName, "tail", "-n", "20", etcdRestoreDir+".log")
+			if logs != nil {
+				log.Printf("[%s] %s", testName, *logs)
+			}
+			return fmt.Errorf("[%s] etcd didn't start within %s", testName, etcdRestoreTimeout)
+		}
+		time.Sleep(time.Second)
+	}
+
+	revision := endpointStatus[0].Status.Header.Revision
+	if revision != snapshotStatus.Revision {
+		return fmt.Errorf("[%s] restored etcd is at revision %d, but the snapshot has revision %d", testName, revision, snapshotStatus.Revision)
+	}
+	position := "revision " + strconv.FormatInt(revision, 10)
+	p.state.recoveryPoint = &RecoveryPoint{
+		Position: &position,
+	}
+	log.Printf("[%s] Etcd restored at revision %d", testName, revision)
+	return nil
+}
+
+// ListDatabases returns the key prefixes up to the configured depth, eg. /registry/deployments
+func (p EtcdFormatProvider) ListDatabases(testName string) ([]string, error) {
+	output, err := p.etcdctl(testName, "get", "", "--prefix", "--keys-only")
+	if err != nil {
+		return nil, err
+	}
+
+	prefixDepth := 2
+	if p.config.PrefixDepth != nil {
+		prefixDepth = *p.config.PrefixDepth
+	}
+
+	prefixes := map[string]bool{}
+	for _, key := range strings.Split(*output, "\n") {
+		if key == "" {
+			continue
+		}
+		parts := strings.Split(key, "/")
+		depth := prefixDepth
+		if strings.HasPrefix(key, "/") {
+			depth++
+		}
+		if len(parts) > depth {
+			parts = parts[:depth]
+		}
+		prefixes[strings.Join(parts, "/")] = true
+	}
+
+	databaseNames := []string{}
+	for prefix := range prefixes {
+		databaseNames = append(databaseNames, prefix)
+	}
+	sort.Strings(databaseNames)
+	return databaseNames, nil
+}
+
+func (p EtcdFormatProvider) GetDatabaseSize(testName string, database string) (*uint64, error) {
+	return nil, fmt.Errorf(`[%s] GetDatabaseSize not available for etcd format`, testName)
+}
+
+func (p EtcdFormatProvider) ListTables(testName string, database string) ([]string, error) {
+	return nil, fmt.Errorf(`[%s] ListTables not available for etcd format`, testName)
+}
+
+// QueryRecord returns the value of the key in the query
+func (p EtcdFormatProvider) QueryRecord(testName string, database string, query string) (map[string]interface{}, error) {
+	output, err := p.etcdctl(testName, "get", query, "--print-value-only")
+	if err != nil {
+		return nil, err
+	}
+	if *output == "" {
+		return nil, fmt.Errorf("[%s] key %s not found", testName, query)
+	}
+	return map[string]interface{}{"value": strings.TrimSuffix(*output, "\n")}, nil
+}
+
+// CountRecords returns the amount of keys with the prefix
+func (p EtcdFormatProvider) CountRecords(testName string, database string, table string, estimate bool) (*uint64, error) {
+	output, err := p.etcdctl(testName, "get", strings.TrimSuffix(database, "/")+"/", "--prefix", "--count-only", "--write-out=json")
+	if err != nil {
+		return nil, err
+	}
+
+	result := EtcdCountResult{}
+	err = json.Unmarshal([]byte(*output), &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result.Count, nil
+}
+
+func (p EtcdFormatProvider) GetRecoveryPoint(testName string) (*RecoveryPoint, error) {
+	return p.state.recoveryPoint, nil
+}
+
+func (p EtcdFormatProvider) etcdctl(testName string, args ...string) (*string, error) {
+	return p.runtimeProvider.Exec(testName, "etcdctl", append([]string{"--endpoints=" + etcdRestoreEndpoint}, args...)...)
+}
+
+func NewEtcdFormatProvider(runtimeProvider runtime.RuntimeProvider, config EtcdConfig) EtcdFormatProvider {
+	etcdFormatProvider := EtcdFormatProvider{
+		runtimeProvider: runtimeProvider,
+		config:          config,
+		state:           &etcdState{},
+	}
+	return etcdFormatProvider
+}
@@ -32,6 +32,9 @@ func (p FileFormatProvider) ListTables(testName string, database string) ([]stri
 func (p FileFormatProvider) QueryRecord(testName string, database string, query string) (map[string]interface{}, error) {
 	return nil, fmt.Errorf(`[%s] QueryRecord not available for file format`, testName)
 }
+func (p FileFormatProvider) CountRecords(testName string, database string, table string, estimate bool) (*uint64, error) {
+	return nil, fmt.Errorf(`[%s] CountRecords not available for file format`, testName)
+}
 
 func NewFileFormatProvider() FileFormatProvider {
 	fileFormatProvider := FileFormatProvider{}
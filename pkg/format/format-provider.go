@@ -1,5 +1,12 @@
 package format
 
+import (
+	"fmt"
+	"time"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/runtime"
+)
+
 type FormatProvider interface {
 	Setup(testName string, dir string) error
 	Destroy(testName string, dir string) error
@@ -9,4 +16,52 @@ type FormatProvider interface {
 	GetDatabaseSize(testName string, database string) (*uint64, error)
 	ListTables(testName string, database string) ([]string, error)
 	QueryRecord(testName string, database string, query string) (map[string]interface{}, error)
+	CountRecords(testName string, database string, table string, estimate bool) (*uint64, error)
+}
+
+// RuntimeFormatProvider is implemented by formats that import the data in a runtime, eg. a docker container
+type RuntimeFormatProvider interface {
+	GetRuntimeProvider() runtime.RuntimeProvider
+}
+
+// RecoveryPointProvider is implemented by formats that know up to which point in time the data was recovered
+type RecoveryPointProvider interface {
+	GetRecoveryPoint(testName string) (*RecoveryPoint, error)
+}
+
+// RestoredDatabasesReceiver is implemented by formats that need to know which databases the backup provider restored, eg. to delete them again
+type RestoredDatabasesReceiver interface {
+	SetRestoredDatabases(testName string, databases []string)
+}
+
+type RecoveryPoint struct {
+	Time     *time.Time `json:"time"`
+	Position *string    `json:"position"`
 }
+
+// ImportError is returned when the import completed, but part of the data failed to import
+type ImportError struct {
+	Errors   int
+	Messages []string
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("import completed with %d errors", e.Errors)
+}
+
+// SeriesProvider is implemented by time-series formats that can count the samples of a series within a time window
+type SeriesProvider interface {
+	CountSamples(testName string, database string, series string, from time.Time, to time.Time) (*uint64, error)
+}
+
+// SchemaProvider is implemented by formats that can describe the schema of a database
+type SchemaProvider interface {
+	GetSchema(testName string, database string) (DatabaseSchema, error)
+}
+
+// DatabaseSchema contains the fields and their type per table, the type is empty when the format doesn't know it
+type DatabaseSchema map[string]map[string]string
+
+// OptionalField is the type of fields that are only found in some of the records (eg. a part of the sampled mongo documents),
+// they aren't reported when they are missing or extra
+const OptionalField = "optional"
@@ -0,0 +1,224 @@
+package format
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/runtime"
+)
+
+const influxdbRestoreTimeout = 5 * time.Minute
+
+type InfluxdbFormatProvider struct {
+	runtimeProvider runtime.RuntimeProvider
+}
+
+type InfluxdbBucket struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func (p InfluxdbFormatProvider) Setup(testName string, dir string) error {
+	return p.runtimeProvider.Setup(testName, dir)
+}
+
+func (p InfluxdbFormatProvider) Destroy(testName string, dir string) error {
+	return p.runtimeProvider.Destroy(testName, dir)
+}
+
+func (p InfluxdbFormatProvider) GetRuntimeProvider() runtime.RuntimeProvider {
+	return p.runtimeProvider
+}
+
+// ImportData waits for influxd and restores the 'influxd backup' directory with 'influx restore'
+func (p InfluxdbFormatProvider) ImportData(testName string, dir string, options []string) error {
+	if len(options) == 0 || strings.HasPrefix(options[len(options)-1], "-") {
+		return fmt.Errorf("[%s] the last import option should be the restored influxdb backup directory", testName)
+	}
+	backupDir := options[len(options)-1]
+
+	// Wait for influxd and its initial setup
+	deadline := time.Now().Add(influxdbRestoreTimeout)
+	for {
+		_, err := p.runtimeProvider.Exec(testName, "influx", "bucket", "list", "--json")
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("[%s] influxdb didn't start within %s: %s", testName, influxdbRestoreTimeout, err)
+		}
+		time.Sleep(time.Second)
+	}
+
+	log.Printf("[%s] Restore %s", testName, backupDir)
+	args := append([]string{"restore"}, options[:len(options)-1]...)
+	_, err := p.runtimeProvider.Exec(testName, "influx", append(args, backupDir)...)
+	return err
+}
+
+// ListDatabases returns the buckets, without the system buckets
+func (p InfluxdbFormatProvider) ListDatabases(testName string) ([]string, error) {
+	output, err := p.runtimeProvider.Exec(testName, "influx", "bucket", "list", "--json")
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := []InfluxdbBucket{}
+	err = json.Unmarshal([]byte(*output), &buckets)
+	if err != nil {
+		return nil, err
+	}
+
+	databaseNames := []string{}
+	for _, bucket := range buckets {
+		if !strings.HasPrefix(bucket.Name, "_") {
+			databaseNames = append(databaseNames, bucket.Name)
+		}
+	}
+	return databaseNames, nil
+}
+
+func (p InfluxdbFormatProvider) GetDatabaseSize(testName string, database string) (*uint64, error) {
+	return nil, fmt.Errorf(`[%s] GetDatabaseSize not available for influxdb format`, testName)
+}
+
+// ListTables returns the measurements of a bucket
+func (p InfluxdbFormatProvider) ListTables(testName string, database string) ([]string, error) {
+	records, err := p.query(testName, `import "influxdata/influxdb/schema"
+schema.measurements(bucket: `+quoteFluxString(database)+`, start: 0)`)
+	if err != nil {
+		return nil, err
+	}
+
+	tableNames := []string{}
+	for _, record := range records {
+		if value, ok := record["_value"].(string); ok {
+			tableNames = append(tableNames, value)
+		}
+	}
+	return tableNames, nil
+}
+
+// QueryRecord runs a Flux query and returns the first record
+func (p InfluxdbFormatProvider) QueryRecord(testName string, database string, query string) (map[string]interface{}, error) {
+	records, err := p.query(testName, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("[%s] query returned no records", testName)
+	}
+	return records[0], nil
+}
+
+// CountRecords returns the amount of points of a measurement
+func (p InfluxdbFormatProvider) CountRecords(testName string, database string, table string, estimate bool) (*uint64, error) {
+	return p.count(testName, database, table, "0", "now()")
+}
+
+// CountSamples counts the points of the measurement in series between from and to
+func (p InfluxdbFormatProvider) CountSamples(testName string, database string, series string, from time.Time, to time.Time) (*uint64, error) {
+	return p.count(testName, database, series, from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339))
+}
+
+func (p InfluxdbFormatProvider) count(testName string, database string, measurement string, start string, stop string) (*uint64, error) {
+	records, err := p.query(testName, `from(bucket: `+quoteFluxString(database)+`)
+  |> range(start: `+start+`, stop: `+stop+`)
+  |> filter(fn: (r) => r._measurement == `+quoteFluxString(measurement)+`)
+  |> count()
+  |> group()
+  |> sum()`)
+	if err != nil {
+		return nil, err
+	}
+
+	count := uint64(0)
+	if len(records) > 0 {
+		count, err = strconv.ParseUint(fmt.Sprint(records[0]["_value"]), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &count, nil
+}
+
+// query runs a Flux query and parses the annotated CSV output into records
+func (p InfluxdbFormatProvider) query(testName string, query string) ([]map[string]interface{}, error) {
+	output, err := p.runtimeProvider.Exec(testName, "influx", "query", "--raw", query)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(strings.NewReader(*output))
+	reader.FieldsPerRecord = -1
+	records := []map[string]interface{}{}
+	var header []string
+	var datatypes []string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(row) == 0 || (len(row) == 1 && row[0] == "") {
+			// A new table starts after an empty line
+			header = nil
+			continue
+		}
+		if row[0] == "#datatype" {
+			datatypes = row
+			continue
+		}
+		if strings.HasPrefix(row[0], "#") {
+			continue
+		}
+		if header == nil {
+			header = row
+			continue
+		}
+
+		record := map[string]interface{}{}
+		for i, column := range header {
+			if column == "" || column == "result" || column == "table" || i >= len(row) {
+				continue
+			}
+			var value interface{} = row[i]
+			if i < len(datatypes) {
+				switch datatypes[i] {
+				case "long", "unsignedLong":
+					if number, err := strconv.ParseInt(row[i], 10, 64); err == nil {
+						value = number
+					}
+				case "double":
+					if number, err := strconv.ParseFloat(row[i], 64); err == nil {
+						value = number
+					}
+				case "boolean":
+					value = row[i] == "true"
+				}
+			}
+			record[column] = value
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func quoteFluxString(value string) string {
+	return strconv.Quote(value)
+}
+
+func NewInfluxdbFormatProvider(runtimeProvider runtime.RuntimeProvider) InfluxdbFormatProvider {
+	influxdbFormatProvider := InfluxdbFormatProvider{
+		runtimeProvider: runtimeProvider,
+	}
+	return influxdbFormatProvider
+}
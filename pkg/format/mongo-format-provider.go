@@ -2,13 +2,36 @@ package format
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/MaxxtonGroup/backup-validator/pkg/runtime"
 )
 
+// eg. 1000 document(s) restored successfully. 2 document(s) failed to restore.
+var mongoFailedDocumentsRegex = regexp.MustCompile(`(\d+) document\(s\) failed to restore`)
+
+// Environment variables that pass the mongorestore config and the shell script with the password into the container
+const (
+	mongoConfigEnv = "BACKUP_VALIDATOR_MONGO_CONFIG"
+	mongoScriptEnv = "BACKUP_VALIDATOR_MONGO_SCRIPT"
+)
+
 type MongoFormatProvider struct {
 	runtimeProvider runtime.RuntimeProvider
+	config          MongoConfig
+	state           *mongoState
+}
+
+type mongoState struct {
+	recoveryPoint *RecoveryPoint
+	shell         *string
 }
 
 type MongoDatabasesResult struct {
@@ -20,6 +43,28 @@ type MongoDatabaseResult struct {
 	Size uint64 `json:"sizeOnDisk"`
 }
 
+type MongoOplogEntry struct {
+	Timestamp struct {
+		Timestamp struct {
+			T int64 `json:"t"`
+			I int64 `json:"i"`
+		} `json:"$timestamp"`
+	} `json:"ts"`
+}
+
+type MongoValidateResult struct {
+	Valid    bool     `json:"valid"`
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+	Records  uint64   `json:"nrecords"`
+}
+
+type MongoIndex struct {
+	Name   string                 `json:"name"`
+	Key    map[string]interface{} `json:"key"`
+	Unique bool                   `json:"unique"`
+}
+
 func (p MongoFormatProvider) Setup(testName string, dir string) error {
 	return p.runtimeProvider.Setup(testName, dir)
 }
@@ -28,19 +73,115 @@ func (p MongoFormatProvider) Destroy(testName string, dir string) error {
 	return p.runtimeProvider.Destroy(testName, dir)
 }
 
+func (p MongoFormatProvider) GetRuntimeProvider() runtime.RuntimeProvider {
+	return p.runtimeProvider
+}
+
 func (p MongoFormatProvider) ImportData(testName string, dir string, options []string) error {
-	_, err := p.runtimeProvider.Exec(testName, "mongorestore", options...)
-	return err
+	authArgs, password, err := p.getAuthArgs()
+	if err != nil {
+		return err
+	}
+	args := append([]string{}, authArgs...)
+	if p.config.OplogReplay && !containsOption(options, "--oplogReplay") {
+		args = append(args, "--oplogReplay")
+	}
+	for _, ns := range p.config.NsInclude {
+		args = append(args, "--nsInclude="+ns)
+	}
+	if len(p.config.NsFrom) != len(p.config.NsTo) {
+		return fmt.Errorf("[%s] mongo.nsFrom and mongo.nsTo should have the same amount of namespaces", testName)
+	}
+	for i := range p.config.NsFrom {
+		args = append(args, "--nsFrom="+p.config.NsFrom[i], "--nsTo="+p.config.NsTo[i])
+	}
+	args = append(args, options...)
+
+	// mongorestore reports failed documents in the summary on stderr, without failing
+	var output *string
+	if password != nil {
+		// The password is written to a config file from the environment, so it isn't part of a command line
+		script := "f=$(mktemp) && printf '%s\\n' \"$" + mongoConfigEnv + "\" > \"$f\" || exit 1; mongorestore --config=\"$f\" \"$@\" 2>&1; rc=$?; rm -f \"$f\"; exit $rc"
+		output, err = p.runtimeProvider.ExecWithEnv(testName, map[string]string{mongoConfigEnv: "password: " + quoteYamlString(*password)}, "sh", append([]string{"-c", script, "sh"}, args...)...)
+	} else {
+		output, err = p.runtimeProvider.Exec(testName, "sh", append([]string{"-c", "exec mongorestore \"$@\" 2>&1", "sh"}, args...)...)
+	}
+	var combinedOutput string
+	var execErr *runtime.ExecError
+	if output != nil {
+		combinedOutput = *output
+	} else if errors.As(err, &execErr) {
+		combinedOutput = execErr.Stdout
+	}
+	if match := mongoFailedDocumentsRegex.FindStringSubmatch(combinedOutput); match != nil && err == nil {
+		failedDocuments, _ := strconv.Atoi(match[1])
+		if failedDocuments > 0 {
+			messages := []string{}
+			for _, line := range strings.Split(combinedOutput, "\n") {
+				if strings.Contains(line, "Failed:") || strings.Contains(line, "error") {
+					messages = append(messages, strings.TrimSpace(line))
+				}
+			}
+			err = &ImportError{
+				Errors:   failedDocuments,
+				Messages: messages,
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// The last replayed oplog entry is the point in time the data is recovered to
+	if containsOption(args, "--oplogReplay") {
+		recoveryPoint, err := p.findOplogRecoveryPoint(testName, options)
+		if err != nil {
+			log.Printf("[%s] Failed to find recovery point: %s", testName, err)
+		}
+		p.state.recoveryPoint = recoveryPoint
+	}
+	return nil
 }
 
-func (p MongoFormatProvider) GetDatabaseSize(testName string, database string) (*uint64, error) {
-	output, err := p.runtimeProvider.Exec(testName, "mongo", "--eval=db.adminCommand( { listDatabases: 1 } )", "--quiet")
+func (p MongoFormatProvider) GetRecoveryPoint(testName string) (*RecoveryPoint, error) {
+	return p.state.recoveryPoint, nil
+}
+
+func (p MongoFormatProvider) findOplogRecoveryPoint(testName string, options []string) (*RecoveryPoint, error) {
+	// mongorestore reads the dump from the positional argument, the --dir option or ./dump
+	dumpDir := "dump"
+	for _, option := range options {
+		if strings.HasPrefix(option, "--dir=") {
+			dumpDir = strings.TrimPrefix(option, "--dir=")
+		} else if !strings.HasPrefix(option, "-") {
+			dumpDir = option
+		}
+	}
+
+	output, err := p.runtimeProvider.Exec(testName, "bash", "-c", "bsondump --quiet \"$1/oplog.bson\" | tail -n 1", "bash", dumpDir)
 	if err != nil {
 		return nil, err
 	}
+	if strings.TrimSpace(*output) == "" {
+		return nil, fmt.Errorf("oplog in %s is empty", dumpDir)
+	}
 
+	entry := MongoOplogEntry{}
+	err = json.Unmarshal([]byte(*output), &entry)
+	if err != nil {
+		return nil, err
+	}
+	recoveryTime := time.Unix(entry.Timestamp.Timestamp.T, 0).UTC()
+	position := fmt.Sprintf("%d:%d", entry.Timestamp.Timestamp.T, entry.Timestamp.Timestamp.I)
+	return &RecoveryPoint{
+		Time:     &recoveryTime,
+		Position: &position,
+	}, nil
+}
+
+func (p MongoFormatProvider) GetDatabaseSize(testName string, database string) (*uint64, error) {
 	result := MongoDatabasesResult{}
-	err = json.Unmarshal([]byte(*output), &result)
+	err := p.eval(testName, "admin", "db.adminCommand( { listDatabases: 1 } )", &result)
 	if err != nil {
 		return nil, err
 	}
@@ -54,13 +195,8 @@ func (p MongoFormatProvider) GetDatabaseSize(testName string, database string) (
 }
 
 func (p MongoFormatProvider) ListDatabases(testName string) ([]string, error) {
-	output, err := p.runtimeProvider.Exec(testName, "mongo", "--eval=db.adminCommand( { listDatabases: 1 } )", "--quiet")
-	if err != nil {
-		return nil, err
-	}
-
 	result := MongoDatabasesResult{}
-	err = json.Unmarshal([]byte(*output), &result)
+	err := p.eval(testName, "admin", "db.adminCommand( { listDatabases: 1 } )", &result)
 	if err != nil {
 		return nil, err
 	}
@@ -73,38 +209,256 @@ func (p MongoFormatProvider) ListDatabases(testName string) ([]string, error) {
 }
 
 func (p MongoFormatProvider) ListTables(testName string, database string) ([]string, error) {
-	output, err := p.runtimeProvider.Exec(testName, "mongo", "--eval=db.getCollectionNames()", "--quiet", database)
+	result := []string{}
+	err := p.eval(testName, database, "db.getCollectionNames()", &result)
 	if err != nil {
 		return nil, err
 	}
+	return result, nil
+}
 
-	result := []string{}
-	err = json.Unmarshal([]byte(*output), &result)
+// QueryRecord evaluates the query in the shell, the result is converted to relaxed extended JSON (eg. {"$oid": "..."} for ObjectIds)
+func (p MongoFormatProvider) QueryRecord(testName string, database string, query string) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	err := p.eval(testName, database, query, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (p MongoFormatProvider) CountRecords(testName string, database string, table string, estimate bool) (*uint64, error) {
+	if table == "" {
+		return nil, fmt.Errorf("[%s] Counting records of the mongo format needs a table, set 'tables' in the rowCount assert", testName)
+	}
+	countFunction := "countDocuments({})"
+	if estimate {
+		countFunction = "estimatedDocumentCount()"
+	}
+	collection, err := json.Marshal(table)
+	if err != nil {
+		return nil, err
+	}
+	var count uint64
+	err = p.eval(testName, database, "db.getCollection("+string(collection)+")."+countFunction, &count)
 	if err != nil {
 		return nil, err
 	}
+	return &count, nil
+}
 
+// ValidateCollection runs validate() on the collection, a full validation also checks the data of the documents
+func (p MongoFormatProvider) ValidateCollection(testName string, database string, collection string, full bool) (*MongoValidateResult, error) {
+	collectionName, err := json.Marshal(collection)
+	if err != nil {
+		return nil, err
+	}
+	result := &MongoValidateResult{}
+	err = p.eval(testName, database, "db.getCollection("+string(collectionName)+").validate({ full: "+strconv.FormatBool(full)+" })", result)
+	if err != nil {
+		return nil, err
+	}
 	return result, nil
 }
 
-func (p MongoFormatProvider) QueryRecord(testName string, database string, query string) (map[string]interface{}, error) {
-	output, err := p.runtimeProvider.Exec(testName, "mongo", "--eval="+query, "--quiet", database)
+// ListIndexes returns the indexes of the collection
+func (p MongoFormatProvider) ListIndexes(testName string, database string, collection string) ([]MongoIndex, error) {
+	collectionName, err := json.Marshal(collection)
 	if err != nil {
 		return nil, err
 	}
+	indexes := []MongoIndex{}
+	err = p.eval(testName, database, "db.getCollection("+string(collectionName)+").getIndexes()", &indexes)
+	if err != nil {
+		return nil, err
+	}
+	return indexes, nil
+}
 
-	result := map[string]interface{}{}
-	err = json.Unmarshal([]byte(*output), &result)
+// GetSchema returns the collections with the field names found in a sample of the documents, mongo has no fixed field types.
+// Fields that are in less than half of the sampled documents are optional.
+func (p MongoFormatProvider) GetSchema(testName string, database string) (DatabaseSchema, error) {
+	collections, err := p.ListTables(testName, database)
 	if err != nil {
 		return nil, err
 	}
+	sampleSize := 100
+	if p.config.SchemaSampleSize != nil {
+		sampleSize = *p.config.SchemaSampleSize
+	}
 
-	return result, nil
+	schema := DatabaseSchema{}
+	for _, collection := range collections {
+		collectionName, err := json.Marshal(collection)
+		if err != nil {
+			return nil, err
+		}
+		documents := []map[string]interface{}{}
+		err = p.eval(testName, database, "db.getCollection("+string(collectionName)+").aggregate([{ $sample: { size: "+strconv.Itoa(sampleSize)+" } }]).toArray()", &documents)
+		if err != nil {
+			return nil, err
+		}
+		fieldCounts := map[string]int{}
+		for _, document := range documents {
+			addMongoFieldNames(fieldCounts, "", document)
+		}
+
+		// The sample is random, so fields that are missing in most documents are optional to keep the comparison stable
+		fields := map[string]string{}
+		for field, count := range fieldCounts {
+			if count*2 >= len(documents) {
+				fields[field] = ""
+			} else {
+				fields[field] = OptionalField
+			}
+		}
+		schema[collection] = fields
+	}
+	return schema, nil
+}
+
+// addMongoFieldNames counts the paths of the fields in the document, eg. address.city
+func addMongoFieldNames(fieldCounts map[string]int, prefix string, document map[string]interface{}) {
+	for name, value := range document {
+		fieldCounts[prefix+name]++
+		if object, ok := value.(map[string]interface{}); ok && !isExtendedJSONValue(object) {
+			addMongoFieldNames(fieldCounts, prefix+name+".", object)
+		}
+	}
+}
+
+// isExtendedJSONValue checks if the object is a value in extended JSON, eg. {"$oid": "..."}
+func isExtendedJSONValue(object map[string]interface{}) bool {
+	for name := range object {
+		if strings.HasPrefix(name, "$") {
+			return true
+		}
+	}
+	return false
+}
+
+// eval evaluates the expression with mongosh or the legacy mongo shell and decodes the JSON output into result
+func (p MongoFormatProvider) eval(testName string, database string, expression string, result interface{}) error {
+	shell, err := p.getShell(testName)
+	if err != nil {
+		return err
+	}
+	authArgs, password, err := p.getAuthArgs()
+	if err != nil {
+		return err
+	}
+
+	// Shell output like NumberLong(1) and ObjectId("...") isn't valid JSON, so stringify the result
+	expression = strings.TrimSuffix(strings.TrimSpace(expression), ";")
+	if shell == "mongosh" {
+		expression = "EJSON.stringify(" + expression + ", { relaxed: true })"
+	} else {
+		expression = "JSON.stringify(" + expression + ")"
+	}
+	var output *string
+	if password != nil {
+		// Authenticate in a script that is written from the environment, so the password isn't part of a command line
+		script, err := p.getAuthScript(*password)
+		if err != nil {
+			return err
+		}
+		script += "print(" + expression + ");"
+		output, err = p.runtimeProvider.ExecWithEnv(testName, map[string]string{mongoScriptEnv: script}, "sh", "-c",
+			"d=$(mktemp -d) && printf '%s\\n' \"$"+mongoScriptEnv+"\" > \"$d/eval.js\" || exit 1; \"$@\" \"$d/eval.js\"; rc=$?; rm -rf \"$d\"; exit $rc",
+			"sh", shell, "--quiet", database)
+		if err != nil {
+			return err
+		}
+	} else {
+		args := append([]string{"--quiet", "--eval=" + expression}, authArgs...)
+		output, err = p.runtimeProvider.Exec(testName, shell, append(args, database)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = json.Unmarshal([]byte(strings.TrimSpace(*output)), result)
+	if err != nil {
+		return fmt.Errorf("[%s] invalid output of %s: %s", testName, shell, err)
+	}
+	return nil
+}
+
+// getShell returns the configured shell, or mongosh when it is available in the container
+func (p MongoFormatProvider) getShell(testName string) (string, error) {
+	if p.config.Shell != nil && *p.config.Shell != "auto" {
+		if *p.config.Shell != "mongosh" && *p.config.Shell != "mongo" {
+			return "", fmt.Errorf("Unsupported mongo shell '%s', should be one of: \"auto\", \"mongosh\" or \"mongo\"", *p.config.Shell)
+		}
+		return *p.config.Shell, nil
+	}
+	if p.state.shell == nil {
+		shell := "mongo"
+		_, err := p.runtimeProvider.Exec(testName, "sh", "-c", "command -v mongosh")
+		if err == nil {
+			shell = "mongosh"
+		}
+		log.Printf("[%s] Using %s shell", testName, shell)
+		p.state.shell = &shell
+	}
+	return *p.state.shell, nil
+}
+
+// getAuthArgs returns the arguments to authenticate with, the password is returned separately so it can be passed outside the command line
+func (p MongoFormatProvider) getAuthArgs() ([]string, *string, error) {
+	if p.config.Username == nil {
+		return []string{}, nil, nil
+	}
+	args := []string{"--username=" + *p.config.Username}
+	var password *string
+	if p.config.Password != nil {
+		password = p.config.Password
+	} else if p.config.PasswordFile != nil {
+		bytes, err := ioutil.ReadFile(*p.config.PasswordFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		passwordFromFile := strings.TrimSpace(string(bytes))
+		password = &passwordFromFile
+	}
+	return append(args, "--authenticationDatabase="+p.getAuthenticationDatabase()), password, nil
+}
+
+func (p MongoFormatProvider) getAuthenticationDatabase() string {
+	if p.config.AuthenticationDatabase != nil {
+		return *p.config.AuthenticationDatabase
+	}
+	return "admin"
+}
+
+// getAuthScript returns a script for the mongo shell that authenticates the connection with the username and password
+func (p MongoFormatProvider) getAuthScript(password string) (string, error) {
+	values, err := json.Marshal([]string{p.getAuthenticationDatabase(), *p.config.Username, password})
+	if err != nil {
+		return "", err
+	}
+	return "var auth = " + string(values) + "; if (!db.getSiblingDB(auth[0]).auth(auth[1], auth[2])) { throw new Error('Authentication failed'); }\n", nil
+}
+
+// quoteYamlString quotes a string for a YAML file, single quotes are escaped by doubling them
+func quoteYamlString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func containsOption(options []string, option string) bool {
+	for _, o := range options {
+		if o == option || strings.HasPrefix(o, option+"=") {
+			return true
+		}
+	}
+	return false
 }
 
-func NewMongoFormatProvider(runtimeProvider runtime.RuntimeProvider) MongoFormatProvider {
+func NewMongoFormatProvider(runtimeProvider runtime.RuntimeProvider, config MongoConfig) MongoFormatProvider {
 	mongoFormatProvider := MongoFormatProvider{
 		runtimeProvider: runtimeProvider,
+		config:          config,
+		state:           &mongoState{},
 	}
 	return mongoFormatProvider
 }
@@ -1,16 +1,62 @@
 package format
 
 import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/MaxxtonGroup/backup-validator/pkg/runtime"
 )
 
+const defaultPostgresDataDir = "/var/lib/postgresql/data"
+
+var postgresIgnoredErrorsRegex = regexp.MustCompile(`errors ignored on restore: (\d+)`)
+
+// Connection options that pg_restore and psql have in common, the second regex matches options with the value in the next argument
+var postgresPsqlOptionRegex = regexp.MustCompile(`^(-[dUhpv]|--(dbname|username|host|port|set|variable)(=|$))`)
+var postgresPsqlOptionValueRegex = regexp.MustCompile(`^(-[dUhpv]|--(dbname|username|host|port|set|variable))$`)
+
 type PostgresqlFormatProvider struct {
 	runtimeProvider runtime.RuntimeProvider
+	config          PostgresqlConfig
+	state           *postgresqlState
+}
+
+type postgresqlState struct {
+	recoveryPoint *RecoveryPoint
+}
+
+var postgresqlRelationKinds = map[string]string{
+	"r": "table",
+	"p": "partitioned table",
+	"v": "view",
+	"m": "materialized view",
+	"f": "foreign table",
+}
+
+type PostgresqlRelation struct {
+	Schema string
+	Name   string
+	Kind   string
+}
+
+type PostgresqlIntegrityCheck struct {
+	Amcheck        bool
+	HeapAllIndexed bool
+	Extensions     []string
+	Roles          []string
+	Sequences      []string
+	Vacuum         bool
 }
 
 type PostgresqlDatabasesResult struct {
@@ -30,8 +76,17 @@ func (p PostgresqlFormatProvider) Destroy(testName string, dir string) error {
 	return p.runtimeProvider.Destroy(testName, dir)
 }
 
+func (p PostgresqlFormatProvider) GetRuntimeProvider() runtime.RuntimeProvider {
+	return p.runtimeProvider
+}
+
 func (p PostgresqlFormatProvider) ImportData(testName string, dir string, options []string) error {
-	_, err := p.runtimeProvider.Exec(testName, "pg_restore", options...)
+	var err error
+	if p.config.Physical != nil {
+		err = p.importPhysicalBackup(testName, options)
+	} else {
+		err = p.importDump(testName, dir, options)
+	}
 	if err != nil {
 		log.Printf("[%s] Import Failed: %s", testName, err.Error())
 	} else {
@@ -50,7 +105,7 @@ func (p PostgresqlFormatProvider) GetDatabaseSize(testName string, database stri
 		return nil, err
 	}
 
-	output, err := p.runtimeProvider.Exec(testName, "psql", "--username="+*psqlUser, *psqlDatabase, "-t", "-c", "select pg_database_size('"+database+"');")
+	output, err := p.execPsql(testName, "--username="+*psqlUser, *psqlDatabase, "-t", "-c", "select pg_database_size('"+database+"');")
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +127,8 @@ func (p PostgresqlFormatProvider) ListDatabases(testName string) ([]string, erro
 		return nil, err
 	}
 
-	output, err := p.runtimeProvider.Exec(testName, "psql", "--username="+*psqlUser, *psqlDatabase, "-t", "-c", "select datname from pg_database;")
+	// Template databases aren't restored, and template0 doesn't accept connections
+	output, err := p.execPsql(testName, "--username="+*psqlUser, *psqlDatabase, "-t", "-c", "select datname from pg_database where datallowconn and not datistemplate;")
 	if err != nil {
 		return nil, err
 	}
@@ -88,67 +144,690 @@ func (p PostgresqlFormatProvider) ListDatabases(testName string) ([]string, erro
 	return databaseNames, nil
 }
 
+// ListTables returns the schema qualified names of the tables, views and foreign tables, eg. billing.invoices
 func (p PostgresqlFormatProvider) ListTables(testName string, database string) ([]string, error) {
+	relations, err := p.ListRelations(testName, database)
+	if err != nil {
+		return nil, err
+	}
+	tableNames := []string{}
+	for _, relation := range relations {
+		tableNames = append(tableNames, relation.Schema+"."+relation.Name)
+	}
+	return tableNames, nil
+}
+
+// ListRelations returns the tables, partitioned tables, views, materialized views and foreign tables outside the system schemas
+func (p PostgresqlFormatProvider) ListRelations(testName string, database string) ([]PostgresqlRelation, error) {
 	psqlUser, err := p.getPostgresUser(testName)
 	if err != nil {
 		return nil, err
 	}
 
-	output, err := p.runtimeProvider.Exec(testName, "psql", "--username="+*psqlUser, database, "-t", "-c", "SELECT table_name FROM information_schema.tables WHERE table_catalog='"+database+"' AND table_type='BASE TABLE';")
+	query := `SELECT n.nspname, c.relname, c.relkind FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE c.relkind IN ('r', 'p', 'v', 'm', 'f') AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg\_toast%' AND n.nspname NOT LIKE 'pg\_temp%'
+ORDER BY n.nspname, c.relname;`
+	output, err := p.execPsql(testName, "--username="+*psqlUser, database, "-t", "-A", "-F", "\t", "-c", query)
 	if err != nil {
 		return nil, err
 	}
-	tableNames := []string{}
-	tables := strings.Split(*output, "\n")
-	for _, table := range tables {
-		tableName := strings.TrimSpace(table)
-		if tableName != "" {
-			tableNames = append(tableNames, tableName)
+	relations := []PostgresqlRelation{}
+	for _, line := range splitLines(*output) {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("[%s] unexpected output of psql: %s", testName, line)
 		}
+		relations = append(relations, PostgresqlRelation{
+			Schema: fields[0],
+			Name:   fields[1],
+			Kind:   postgresqlRelationKinds[fields[2]],
+		})
 	}
+	return relations, nil
+}
 
-	return tableNames, nil
+// GetSchema returns the columns and their type of the tables, views and foreign tables
+func (p PostgresqlFormatProvider) GetSchema(testName string, database string) (DatabaseSchema, error) {
+	relations, err := p.ListRelations(testName, database)
+	if err != nil {
+		return nil, err
+	}
+	schema := DatabaseSchema{}
+	for _, relation := range relations {
+		schema[relation.Schema+"."+relation.Name] = map[string]string{}
+	}
+
+	psqlUser, err := p.getPostgresUser(testName)
+	if err != nil {
+		return nil, err
+	}
+	query := `SELECT n.nspname || '.' || c.relname, a.attname, format_type(a.atttypid, a.atttypmod) FROM pg_attribute a
+JOIN pg_class c ON c.oid = a.attrelid JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE a.attnum > 0 AND NOT a.attisdropped AND c.relkind IN ('r', 'p', 'v', 'm', 'f');`
+	output, err := p.execPsql(testName, "--username="+*psqlUser, database, "-t", "-A", "-F", "\t", "-c", query)
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range splitLines(*output) {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("[%s] unexpected output of psql: %s", testName, line)
+		}
+		if columns, ok := schema[fields[0]]; ok {
+			columns[fields[1]] = fields[2]
+		}
+	}
+	return schema, nil
 }
 
 func (p PostgresqlFormatProvider) QueryRecord(testName string, database string, query string) (map[string]interface{}, error) {
-	return nil, fmt.Errorf("[%s] QueryRecord not supported for postgresql yet", testName)
+	psqlUser, err := p.getPostgresUser(testName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Let postgres serialize the first row as json
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	output, err := p.execPsql(testName, "--username="+*psqlUser, database, "-t", "-A", "-c", "SELECT row_to_json(q) FROM ("+query+") q LIMIT 1;")
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(*output) == "" {
+		return nil, fmt.Errorf("[%s] query returned no records", testName)
+	}
+
+	result := map[string]interface{}{}
+	err = json.Unmarshal([]byte(*output), &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-func (p PostgresqlFormatProvider) getPostgresUser(testName string) (*string, error) {
-	envs, err := p.runtimeProvider.Exec(testName, "env")
+func (p PostgresqlFormatProvider) CountRecords(testName string, database string, table string, estimate bool) (*uint64, error) {
+	if table == "" {
+		return nil, fmt.Errorf("[%s] Counting records of the postgresql format needs a table, set 'tables' in the rowCount assert", testName)
+	}
+	psqlUser, err := p.getPostgresUser(testName)
 	if err != nil {
 		return nil, err
 	}
-	envList := strings.Split(*envs, "\n")
-	psqlUser := "postgres"
-	for _, env := range envList {
-		if strings.HasPrefix(env, "POSTGRES_USER=") {
-			psqlUser = strings.TrimPrefix(env, "POSTGRES_USER=")
+
+	args := []string{"--username=" + *psqlUser, database, "-t", "-q", "-v", "ON_ERROR_STOP=1"}
+	count := "count(*)"
+	if estimate {
+		// Use the row estimate of the planner, which avoids a full table scan.
+		// pg_restore doesn't analyze the tables, ANALYZE samples the table to update the estimate,
+		// the table is counted when it has never been analyzed (reltuples is -1 since postgres 14)
+		args = append(args, "-c", "ANALYZE "+quotePostgresTableName(table)+";")
+		count = "CASE WHEN reltuples < 0 THEN (SELECT count(*) FROM " + quotePostgresTableName(table) + ") ELSE reltuples::bigint END" +
+			" FROM pg_class WHERE oid = '" + strings.ReplaceAll(quotePostgresTableName(table), "'", "''") + "'::regclass"
+	} else {
+		count += " FROM " + quotePostgresTableName(table)
+	}
+	output, err := p.execPsql(testName, append(args, "-c", "SELECT "+count+";")...)
+	if err != nil {
+		return nil, err
+	}
+	records, err := strconv.ParseUint(strings.TrimSpace(*output), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &records, nil
+}
+
+func (p PostgresqlFormatProvider) GetRecoveryPoint(testName string) (*RecoveryPoint, error) {
+	return p.state.recoveryPoint, nil
+}
+
+// CheckIntegrity checks the restored database for corrupt and invalid indexes, unvalidated constraints and missing objects, it returns the problems that are found
+func (p PostgresqlFormatProvider) CheckIntegrity(testName string, database string, check PostgresqlIntegrityCheck) ([]string, error) {
+	problems := []string{}
+
+	if check.Amcheck {
+		amcheckProblems, err := p.runAmcheck(testName, database, check.HeapAllIndexed)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, amcheckProblems...)
+	}
+
+	// Indexes that failed to build (eg. unique violations) are left behind as invalid
+	invalidIndexes, err := p.queryColumn(testName, database, "SELECT indexrelid::regclass FROM pg_index WHERE NOT indisvalid;")
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range invalidIndexes {
+		problems = append(problems, "invalid index "+index)
+	}
+
+	// Constraints that are added with NOT VALID, eg. when validating the foreign key failed
+	invalidConstraints, err := p.queryColumn(testName, database, "SELECT conrelid::regclass || '.' || conname FROM pg_constraint WHERE NOT convalidated;")
+	if err != nil {
+		return nil, err
+	}
+	for _, constraint := range invalidConstraints {
+		problems = append(problems, "unvalidated constraint "+constraint)
+	}
+
+	missing, err := p.findMissing(testName, database, "SELECT extname FROM pg_extension;", check.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	for _, extension := range missing {
+		problems = append(problems, "missing extension "+extension)
+	}
+	missing, err = p.findMissing(testName, database, "SELECT rolname FROM pg_roles;", check.Roles)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range missing {
+		problems = append(problems, "missing role "+role)
+	}
+	missing, err = p.findMissing(testName, database, "SELECT sequencename FROM pg_sequences UNION SELECT schemaname || '.' || sequencename FROM pg_sequences;", check.Sequences)
+	if err != nil {
+		return nil, err
+	}
+	for _, sequence := range missing {
+		problems = append(problems, "missing sequence "+sequence)
+	}
+
+	if check.Vacuum {
+		// VACUUM reads every data page, so unreadable pages are reported as errors
+		log.Printf("[%s] Vacuum analyze %s", testName, database)
+		_, err = p.queryColumn(testName, database, "VACUUM ANALYZE;")
+		var execErr *runtime.ExecError
+		if errors.As(err, &execErr) {
+			problems = append(problems, "vacuum failed: "+strings.TrimSpace(execErr.Stderr))
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return problems, nil
+}
+
+// runAmcheck verifies the btree indexes with pg_amcheck, or with the amcheck extension on versions before postgres 14
+func (p PostgresqlFormatProvider) runAmcheck(testName string, database string, heapAllIndexed bool) ([]string, error) {
+	psqlUser, err := p.getPostgresUser(testName)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = p.runtimeProvider.Exec(testName, "sh", "-c", "command -v pg_amcheck")
+	if err == nil {
+		args := []string{"--username=" + *psqlUser, "--install-missing", "--database=" + database}
+		if heapAllIndexed {
+			args = append(args, "--heapallindexed")
+		}
+		// pg_amcheck exits with 2 when corruption is found
+		output, err := p.execPostgresTool(testName, "pg_amcheck", args...)
+		var execErr *runtime.ExecError
+		if errors.As(err, &execErr) && execErr.ExitCode == 2 {
+			return splitLines(execErr.Stdout), nil
+		} else if err != nil {
+			return nil, err
+		}
+		return splitLines(*output), nil
+	}
+
+	checkCall := "bt_index_check(r.oid)"
+	if heapAllIndexed {
+		checkCall = "bt_index_check(r.oid, true)"
+	}
+	return p.queryColumn(testName, database, `CREATE EXTENSION IF NOT EXISTS amcheck;
+CREATE TEMP TABLE amcheck_result (name text, error text);
+DO $$
+DECLARE r record;
+BEGIN
+  FOR r IN SELECT c.oid, c.oid::regclass::text AS name FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid JOIN pg_am am ON am.oid = c.relam
+    WHERE am.amname = 'btree' AND i.indisvalid AND i.indisready AND c.relpersistence <> 't' LOOP
+    BEGIN
+      PERFORM `+checkCall+`;
+    EXCEPTION WHEN others THEN
+      INSERT INTO amcheck_result VALUES (r.name, SQLERRM);
+    END;
+  END LOOP;
+END $$;
+SELECT 'corrupt index ' || name || ': ' || error FROM amcheck_result;`)
+}
+
+// findMissing returns the expected names that aren't returned by the query
+func (p PostgresqlFormatProvider) findMissing(testName string, database string, query string, expected []string) ([]string, error) {
+	if len(expected) == 0 {
+		return nil, nil
+	}
+	names, err := p.queryColumn(testName, database, query)
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]bool{}
+	for _, name := range names {
+		existing[name] = true
+	}
+	missing := []string{}
+	for _, name := range expected {
+		if !existing[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+// importDump restores a pg_restore archive, or runs plain SQL and pg_dumpall output through psql
+func (p PostgresqlFormatProvider) importDump(testName string, dir string, options []string) error {
+	// The dump file is the last argument, other options are passed to pg_restore or psql
+	var dumpFile string
+	args := options
+	if len(options) > 0 && !strings.HasPrefix(options[len(options)-1], "-") {
+		dumpFile = options[len(options)-1]
+		args = options[:len(options)-1]
+	}
+	if dumpFile == "" {
+		// pg_restore reads from stdin without a file
+		return p.runImport(testName, "pg_restore \"$@\"", options...)
+	}
+
+	dumpType := "auto"
+	if p.config.DumpType != nil {
+		dumpType = *p.config.DumpType
+	}
+	compressed := strings.HasSuffix(dumpFile, ".gz")
+	if dumpType == "auto" {
+		var err error
+		dumpType, compressed, err = detectPostgresDumpType(hostPath(dir, dumpFile))
+		if err != nil {
+			log.Printf("[%s] Unable to detect dump type of %s, falling back to pg_restore: %s", testName, dumpFile, err)
+			dumpType = "custom"
+		}
+	}
+	log.Printf("[%s] Importing %s dump %s", testName, dumpType, dumpFile)
+
+	readDump := "cat \"$1\""
+	if compressed {
+		readDump = "gunzip -c \"$1\""
+	}
+
+	switch dumpType {
+	case "custom", "directory", "tar":
+		if compressed {
+			return p.runImport(testName, readDump+" | pg_restore \"${@:2}\"", append([]string{dumpFile}, args...)...)
+		}
+		return p.runImport(testName, "pg_restore \"$@\"", options...)
+	case "plain", "dumpall":
+		psqlUser, err := p.getPostgresUser(testName)
+		if err != nil {
+			return err
+		}
+		psqlDatabase, err := p.getPostgresDatabase(testName)
+		if err != nil {
+			return err
+		}
+		onErrorStop := "1"
+		if p.config.OnErrorStop != nil && !*p.config.OnErrorStop {
+			onErrorStop = "0"
+		}
+		psqlArgs := []string{"--quiet", "--no-psqlrc", "-v", "ON_ERROR_STOP=" + onErrorStop, "--username=" + *psqlUser, "--dbname=" + *psqlDatabase}
+		psqlArgs = append(psqlArgs, psqlImportOptions(testName, args)...)
+
+		if dumpType == "dumpall" {
+			// The role of the current user already exists in the container
+			filter := " | sed -e \"/^CREATE ROLE $2;$/d\""
+			return p.runImport(testName, readDump+filter+" | psql \"${@:3}\"", append([]string{dumpFile, *psqlUser}, psqlArgs...)...)
+		}
+		return p.runImport(testName, readDump+" | psql \"${@:2}\"", append([]string{dumpFile}, psqlArgs...)...)
+	}
+	return fmt.Errorf("[%s] Unsupported dump type '%s', should be one of: \"auto\", \"custom\", \"directory\", \"tar\", \"plain\" or \"dumpall\"", testName, dumpType)
+}
+
+// runImport runs the import script with bash, errors are only reported as an ImportError when the tool got to the end of the dump:
+// pg_restore printed the number of ignored errors, or psql finished without ON_ERROR_STOP. Otherwise the import was aborted.
+func (p PostgresqlFormatProvider) runImport(testName string, script string, args ...string) error {
+	output, err := p.runtimeProvider.Exec(testName, "bash", append([]string{"-c", "set -o pipefail; { " + script + "; } 2>&1", "bash"}, args...)...)
+	var combinedOutput string
+	var execErr *runtime.ExecError
+	if output != nil {
+		combinedOutput = *output
+	} else if errors.As(err, &execErr) {
+		combinedOutput = execErr.Stdout
+	} else if err != nil {
+		return err
+	}
+
+	messages := []string{}
+	for _, line := range strings.Split(combinedOutput, "\n") {
+		if strings.Contains(line, "ERROR:") || strings.Contains(line, "error:") {
+			messages = append(messages, strings.TrimSpace(line))
+		}
+	}
+
+	if match := postgresIgnoredErrorsRegex.FindStringSubmatch(combinedOutput); match != nil {
+		errorCount, _ := strconv.Atoi(match[1])
+		if errorCount > 0 {
+			return &ImportError{
+				Errors:   errorCount,
+				Messages: messages,
+			}
+		}
+	}
+	if err != nil {
+		if len(messages) > 0 {
+			return fmt.Errorf("[%s] Import aborted: %s: %s", testName, strings.Join(messages, "; "), err)
+		}
+		return err
+	}
+	if len(messages) > 0 {
+		return &ImportError{
+			Errors:   len(messages),
+			Messages: messages,
+		}
+	}
+	return nil
+}
+
+// psqlImportOptions keeps the connection options of the import options that psql understands, the other options are meant for pg_restore
+func psqlImportOptions(testName string, options []string) []string {
+	psqlOptions := []string{}
+	for i := 0; i < len(options); i++ {
+		option := options[i]
+		if postgresPsqlOptionRegex.MatchString(option) {
+			psqlOptions = append(psqlOptions, option)
+			if postgresPsqlOptionValueRegex.MatchString(option) && i+1 < len(options) {
+				i++
+				psqlOptions = append(psqlOptions, options[i])
+			}
+			continue
+		}
+		log.Printf("[%s] Ignoring import option %s, it isn't supported by psql", testName, option)
+		// Skip the value of the ignored option
+		if i+1 < len(options) && !strings.HasPrefix(options[i+1], "-") {
+			i++
+		}
+	}
+	return psqlOptions
+}
+
+// importPhysicalBackup restores a pgBackRest or WAL-G base backup into the data directory and waits for the recovery to finish
+func (p PostgresqlFormatProvider) importPhysicalBackup(testName string, options []string) error {
+	physical := p.config.Physical
+	user := "postgres"
+	if physical.User != nil {
+		user = *physical.User
+	}
+	dataDir, err := p.getPostgresDataDir(testName)
+	if err != nil {
+		return err
+	}
+	recoveryTimeout := time.Hour
+	if physical.RecoveryTimeout != nil {
+		recoveryTimeout, err = time.ParseDuration(*physical.RecoveryTimeout)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The entrypoint of the image starts postgres on the data directory, unless docker.command is overridden
+	_, err = p.runtimeProvider.ExecAsUser(testName, user, "pg_ctl", "-D", *dataDir, "status")
+	if err == nil {
+		return fmt.Errorf("[%s] postgres is already running on %s, override docker.command (eg. [\"sleep\", \"infinity\"]) to restore a physical backup", testName, *dataDir)
+	}
+
+	// Clear data directory
+	log.Printf("[%s] Prepare data directory %s", testName, *dataDir)
+	_, err = p.runtimeProvider.ExecRoot(testName, "bash", "-c", "mkdir -p \""+*dataDir+"\" && find \""+*dataDir+"\" -mindepth 1 -delete && chown "+user+" \""+*dataDir+"\" && chmod 700 \""+*dataDir+"\"")
+	if err != nil {
+		return err
+	}
+
+	// Restore base backup
+	switch physical.Tool {
+	case "pgbackrest":
+		args := []string{"--pg1-path=" + *dataDir}
+		if physical.Stanza != nil {
+			args = append(args, "--stanza="+*physical.Stanza)
+		}
+		if physical.Backup != nil {
+			args = append(args, "--set="+*physical.Backup)
+		}
+		if physical.TargetTime != nil {
+			args = append(args, "--type=time", "--target="+*physical.TargetTime, "--target-action=promote")
+		} else if physical.TargetLsn != nil {
+			args = append(args, "--type=lsn", "--target="+*physical.TargetLsn, "--target-action=promote")
+		}
+		args = append(args, options...)
+		args = append(args, "restore")
+
+		log.Printf("[%s] Restore base backup with pgBackRest", testName)
+		_, err = p.runtimeProvider.ExecAsUser(testName, user, "pgbackrest", args...)
+		if err != nil {
+			return err
+		}
+	case "wal-g":
+		backupName := "LATEST"
+		if physical.Backup != nil {
+			backupName = *physical.Backup
+		}
+		args := append([]string{}, options...)
+		args = append(args, "backup-fetch", *dataDir, backupName)
+
+		log.Printf("[%s] Restore base backup with WAL-G", testName)
+		_, err = p.runtimeProvider.ExecAsUser(testName, user, "wal-g", args...)
+		if err != nil {
+			return err
+		}
+
+		// Configure WAL replay
+		recoveryConfig := []string{"restore_command = 'wal-g wal-fetch \"%f\" \"%p\"'"}
+		if physical.TargetTime != nil {
+			recoveryConfig = append(recoveryConfig, "recovery_target_time = '"+*physical.TargetTime+"'", "recovery_target_action = 'promote'")
+		} else if physical.TargetLsn != nil {
+			recoveryConfig = append(recoveryConfig, "recovery_target_lsn = '"+*physical.TargetLsn+"'", "recovery_target_action = 'promote'")
+		}
+		script := fmt.Sprintf("printf '%%s\\n' \"$@\" >> \"%s/postgresql.auto.conf\" && touch \"%s/recovery.signal\"", *dataDir, *dataDir)
+		_, err = p.runtimeProvider.ExecAsUser(testName, user, "bash", append([]string{"-c", script, "bash"}, recoveryConfig...)...)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("[%s] Unsupported physical backup tool '%s', should be one of: \"pgbackrest\" or \"wal-g\"", testName, physical.Tool)
+	}
+
+	// Start postgres
+	log.Printf("[%s] Start postgres", testName)
+	_, err = p.runtimeProvider.ExecAsUser(testName, user, "pg_ctl", "-D", *dataDir, "-l", "/tmp/postgresql-recovery.log", "-W", "start")
+	if err != nil {
+		return err
+	}
+
+	// Wait for recovery to complete
+	deadline := time.Now().Add(recoveryTimeout)
+	for {
+		output, err := p.execPsql(testName, "--username="+user, "postgres", "-t", "-c", "select pg_is_in_recovery();")
+		if err == nil && strings.TrimSpace(*output) == "f" {
 			break
 		}
+		_, statusErr := p.runtimeProvider.ExecAsUser(testName, user, "pg_ctl", "-D", *dataDir, "status")
+		if statusErr != nil {
+			logs, _ := p.runtimeProvider.Exec(testName, "tail", "-n", "20", "/tmp/postgresql-recovery.log")
+			if logs != nil {
+				log.Printf("[%s] %s", testName, *logs)
+			}
+			return fmt.Errorf("[%s] postgres stopped during recovery", testName)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("[%s] recovery didn't finish within %s", testName, recoveryTimeout)
+		}
+		time.Sleep(5 * time.Second)
+	}
+
+	// Find recovery point
+	output, err := p.execPsql(testName, "--username="+user, "postgres", "-t", "-A", "-F", "|", "-c", "select coalesce(to_char(pg_last_xact_replay_timestamp() at time zone 'UTC', 'YYYY-MM-DD\"T\"HH24:MI:SS.US\"Z\"'), ''), coalesce(pg_last_wal_replay_lsn()::text, '');")
+	if err != nil {
+		return err
+	}
+	recoveryPoint := &RecoveryPoint{}
+	parts := strings.Split(strings.TrimSpace(*output), "|")
+	if len(parts) == 2 {
+		if parts[0] != "" {
+			recoveryTime, err := time.Parse(time.RFC3339Nano, parts[0])
+			if err != nil {
+				return err
+			}
+			recoveryPoint.Time = &recoveryTime
+		}
+		if parts[1] != "" {
+			recoveryPoint.Position = &parts[1]
+		}
 	}
-	return &psqlUser, nil
+	p.state.recoveryPoint = recoveryPoint
+	log.Printf("[%s] Recovery complete (time: %v, lsn: %v)", testName, parts[0], parts[len(parts)-1])
+
+	return nil
+}
+
+// execPsql runs psql as the owner of the cluster for physical backups, so peer authentication of the restored cluster is accepted
+func (p PostgresqlFormatProvider) execPsql(testName string, args ...string) (*string, error) {
+	return p.execPostgresTool(testName, "psql", args...)
+}
+
+// execPostgresTool runs a postgres client tool, as the OS user of the cluster for physical backups
+func (p PostgresqlFormatProvider) execPostgresTool(testName string, command string, args ...string) (*string, error) {
+	if p.config.Physical != nil {
+		user := "postgres"
+		if p.config.Physical.User != nil {
+			user = *p.config.Physical.User
+		}
+		return p.runtimeProvider.ExecAsUser(testName, user, command, args...)
+	}
+	return p.runtimeProvider.Exec(testName, command, args...)
+}
+
+// queryColumn runs the query and returns the first column of every row
+func (p PostgresqlFormatProvider) queryColumn(testName string, database string, query string) ([]string, error) {
+	psqlUser, err := p.getPostgresUser(testName)
+	if err != nil {
+		return nil, err
+	}
+	output, err := p.execPsql(testName, "--username="+*psqlUser, database, "-t", "-A", "-v", "ON_ERROR_STOP=1", "-c", query)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(*output), nil
+}
+
+func (p PostgresqlFormatProvider) getPostgresUser(testName string) (*string, error) {
+	return p.getContainerEnv(testName, "POSTGRES_USER", "postgres")
 }
 
 func (p PostgresqlFormatProvider) getPostgresDatabase(testName string) (*string, error) {
+	return p.getContainerEnv(testName, "POSTGRES_DB", "postgres")
+}
+
+func (p PostgresqlFormatProvider) getPostgresDataDir(testName string) (*string, error) {
+	if p.config.Physical != nil && p.config.Physical.DataDir != nil {
+		return p.config.Physical.DataDir, nil
+	}
+	return p.getContainerEnv(testName, "PGDATA", defaultPostgresDataDir)
+}
+
+func (p PostgresqlFormatProvider) getContainerEnv(testName string, name string, defaultValue string) (*string, error) {
 	envs, err := p.runtimeProvider.Exec(testName, "env")
 	if err != nil {
 		return nil, err
 	}
 	envList := strings.Split(*envs, "\n")
-	psqlUser := "postgres"
+	value := defaultValue
 	for _, env := range envList {
-		if strings.HasPrefix(env, "POSTGRES_DB=") {
-			psqlUser = strings.TrimPrefix(env, "POSTGRES_DB=")
+		if strings.HasPrefix(env, name+"=") {
+			value = strings.TrimPrefix(env, name+"=")
 			break
 		}
 	}
-	return &psqlUser, nil
+	return &value, nil
+}
+
+// detectPostgresDumpType inspects the header of a dump file to find out how it should be imported
+func detectPostgresDumpType(file string) (string, bool, error) {
+	stats, err := os.Stat(file)
+	if err != nil {
+		return "", false, err
+	}
+	if stats.IsDir() {
+		return "directory", false, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+
+	header := make([]byte, 4096)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", false, err
+	}
+	header = header[:n]
+
+	compressed := false
+	if bytes.HasPrefix(header, []byte{0x1f, 0x8b}) {
+		compressed = true
+		_, err = f.Seek(0, io.SeekStart)
+		if err != nil {
+			return "", false, err
+		}
+		gzipReader, err := gzip.NewReader(f)
+		if err != nil {
+			return "", false, err
+		}
+		defer gzipReader.Close()
+		header = make([]byte, 4096)
+		n, err = io.ReadFull(gzipReader, header)
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return "", false, err
+		}
+		header = header[:n]
+	}
+
+	switch {
+	case bytes.HasPrefix(header, []byte("PGDMP")):
+		return "custom", compressed, nil
+	case len(header) > 262 && string(header[257:262]) == "ustar":
+		return "tar", compressed, nil
+	case bytes.Contains(header, []byte("PostgreSQL database cluster dump")):
+		return "dumpall", compressed, nil
+	}
+	return "plain", compressed, nil
+}
+
+func quotePostgresIdentifier(name string) string {
+	return "\"" + strings.ReplaceAll(name, "\"", "\"\"") + "\""
+}
+
+// quotePostgresTableName quotes a table name, which may be qualified with the schema (eg. billing.invoices)
+func quotePostgresTableName(name string) string {
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) == 2 {
+		return quotePostgresIdentifier(parts[0]) + "." + quotePostgresIdentifier(parts[1])
+	}
+	return quotePostgresIdentifier(name)
+}
+
+// hostPath translates a path inside the container to the mounted path on the host
+func hostPath(dir string, path string) string {
+	if strings.HasPrefix(path, "/mnt/host/") {
+		return filepath.Join(dir, strings.TrimPrefix(path, "/mnt/host/"))
+	}
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, "workdir", path)
 }
 
-func NewPostgresqlFormatProvider(runtimeProvider runtime.RuntimeProvider) PostgresqlFormatProvider {
+func NewPostgresqlFormatProvider(runtimeProvider runtime.RuntimeProvider, postgresqlConfig PostgresqlConfig) PostgresqlFormatProvider {
 	postgresqlFormatProvider := PostgresqlFormatProvider{
 		runtimeProvider: runtimeProvider,
+		config:          postgresqlConfig,
+		state:           &postgresqlState{},
 	}
 	return postgresqlFormatProvider
 }
@@ -0,0 +1,245 @@
+package format
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/runtime"
+)
+
+const (
+	prometheusRestoreAddress = "127.0.0.1:9091"
+	prometheusRestoreDir     = "/tmp/prometheus-restore"
+	prometheusRestoreTimeout = 10 * time.Minute
+)
+
+type PrometheusFormatProvider struct {
+	runtimeProvider runtime.RuntimeProvider
+	state           *prometheusState
+}
+
+type prometheusState struct {
+	recoveryPoint *RecoveryPoint
+}
+
+type PrometheusBlockMeta struct {
+	MinTime int64 `json:"minTime"`
+	MaxTime int64 `json:"maxTime"`
+}
+
+type PrometheusResponse struct {
+	Status string          `json:"status"`
+	Error  string          `json:"error"`
+	Data   json.RawMessage `json:"data"`
+}
+
+type PrometheusQueryData struct {
+	ResultType string                   `json:"resultType"`
+	Result     []PrometheusVectorSample `json:"result"`
+}
+
+type PrometheusVectorSample struct {
+	Metric map[string]string `json:"metric"`
+	Value  []interface{}     `json:"value"`
+}
+
+func (p PrometheusFormatProvider) Setup(testName string, dir string) error {
+	return p.runtimeProvider.Setup(testName, dir)
+}
+
+func (p PrometheusFormatProvider) Destroy(testName string, dir string) error {
+	return p.runtimeProvider.Destroy(testName, dir)
+}
+
+func (p PrometheusFormatProvider) GetRuntimeProvider() runtime.RuntimeProvider {
+	return p.runtimeProvider
+}
+
+// ImportData starts a separate prometheus on a copy of the restored TSDB snapshot
+func (p PrometheusFormatProvider) ImportData(testName string, dir string, options []string) error {
+	if len(options) == 0 || strings.HasPrefix(options[len(options)-1], "-") {
+		return fmt.Errorf("[%s] the last import option should be the restored TSDB snapshot directory", testName)
+	}
+	snapshotDir := options[len(options)-1]
+
+	// The newest block is the point in time the data is recovered to
+	metaFiles, err := filepath.Glob(filepath.Join(hostPath(dir, snapshotDir), "*", "meta.json"))
+	if err != nil {
+		return err
+	}
+	if len(metaFiles) == 0 {
+		return fmt.Errorf("[%s] TSDB snapshot %s contains no blocks", testName, snapshotDir)
+	}
+	var maxTime int64
+	for _, metaFile := range metaFiles {
+		bytes, err := ioutil.ReadFile(metaFile)
+		if err != nil {
+			return err
+		}
+		meta := PrometheusBlockMeta{}
+		err = json.Unmarshal(bytes, &meta)
+		if err != nil {
+			return fmt.Errorf("[%s] invalid block %s: %s", testName, metaFile, err)
+		}
+		if meta.MaxTime > maxTime {
+			maxTime = meta.MaxTime
+		}
+	}
+	recoveryTime := time.Unix(0, maxTime*int64(time.Millisecond)).UTC()
+	p.state.recoveryPoint = &RecoveryPoint{
+		Time: &recoveryTime,
+	}
+	log.Printf("[%s] TSDB snapshot contains %d blocks, the newest block ends at %s", testName, len(metaFiles), recoveryTime.Format(time.RFC3339))
+
+	// Start prometheus without scrape targets and retention, so the restored blocks are kept as is
+	args := "--storage.tsdb.path=" + prometheusRestoreDir + " --storage.tsdb.retention.time=100y --web.listen-address=" + prometheusRestoreAddress + " --config.file=" + prometheusRestoreDir + ".yml"
+	for _, option := range options[:len(options)-1] {
+		args += " '" + strings.ReplaceAll(option, "'", "'\\''") + "'"
+	}
+	_, err = p.runtimeProvider.Exec(testName, "sh", "-c", "rm -rf \"$1\" && cp -r \"$2\" \"$1\" && echo '{}' > \"$1.yml\" && (nohup prometheus "+args+" > \"$1.log\" 2>&1 &)", "sh", prometheusRestoreDir, snapshotDir)
+	if err != nil {
+		return err
+	}
+
+	// Wait for prometheus to load the blocks
+	deadline := time.Now().Add(prometheusRestoreTimeout)
+	for {
+		_, err := p.runtimeProvider.Exec(testName, "wget", "-q", "-O", "/dev/null", "http://"+prometheusRestoreAddress+"/-/ready")
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			logs, _ := p.runtimeProvider.Exec(testName, "tail", "-n", "20", prometheusRestoreDir+".log")
+			if logs != nil {
+				log.Printf("[%s] %s", testName, *logs)
+			}
+			return fmt.Errorf("[%s] prometheus didn't start within %s", testName, prometheusRestoreTimeout)
+		}
+		time.Sleep(time.Second)
+	}
+	return nil
+}
+
+// ListDatabases returns the metric names
+func (p PrometheusFormatProvider) ListDatabases(testName string) ([]string, error) {
+	metricNames := []string{}
+	err := p.api(testName, "/api/v1/label/__name__/values", url.Values{}, &metricNames)
+	if err != nil {
+		return nil, err
+	}
+	return metricNames, nil
+}
+
+func (p PrometheusFormatProvider) GetDatabaseSize(testName string, database string) (*uint64, error) {
+	return nil, fmt.Errorf(`[%s] GetDatabaseSize not available for prometheus format`, testName)
+}
+
+// ListTables returns the label names of a metric
+func (p PrometheusFormatProvider) ListTables(testName string, database string) ([]string, error) {
+	labelNames := []string{}
+	err := p.api(testName, "/api/v1/labels", url.Values{"match[]": {database}}, &labelNames)
+	if err != nil {
+		return nil, err
+	}
+	return labelNames, nil
+}
+
+// QueryRecord runs a PromQL query at the end of the newest block and returns the first sample
+func (p PrometheusFormatProvider) QueryRecord(testName string, database string, query string) (map[string]interface{}, error) {
+	params := url.Values{"query": {query}}
+	if p.state.recoveryPoint != nil && p.state.recoveryPoint.Time != nil {
+		params.Set("time", strconv.FormatInt(p.state.recoveryPoint.Time.Unix(), 10))
+	}
+	data := PrometheusQueryData{}
+	err := p.api(testName, "/api/v1/query", params, &data)
+	if err != nil {
+		return nil, err
+	}
+	if len(data.Result) == 0 {
+		return nil, fmt.Errorf("[%s] query returned no records", testName)
+	}
+
+	result := map[string]interface{}{}
+	for key, value := range data.Result[0].Metric {
+		result[key] = value
+	}
+	if len(data.Result[0].Value) == 2 {
+		result["value"] = data.Result[0].Value[1]
+	}
+	return result, nil
+}
+
+// CountRecords returns the amount of series of a metric
+func (p PrometheusFormatProvider) CountRecords(testName string, database string, table string, estimate bool) (*uint64, error) {
+	series := []map[string]string{}
+	err := p.api(testName, "/api/v1/series", url.Values{"match[]": {database}, "start": {"0"}}, &series)
+	if err != nil {
+		return nil, err
+	}
+	count := uint64(len(series))
+	return &count, nil
+}
+
+// CountSamples counts the samples of the series selector between from and to
+func (p PrometheusFormatProvider) CountSamples(testName string, database string, series string, from time.Time, to time.Time) (*uint64, error) {
+	window := int64(to.Sub(from).Seconds())
+	if window <= 0 {
+		return nil, fmt.Errorf("[%s] invalid time window", testName)
+	}
+	params := url.Values{
+		"query": {fmt.Sprintf("sum(count_over_time(%s[%ds]))", series, window)},
+		"time":  {strconv.FormatInt(to.Unix(), 10)},
+	}
+	data := PrometheusQueryData{}
+	err := p.api(testName, "/api/v1/query", params, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	count := uint64(0)
+	if len(data.Result) > 0 && len(data.Result[0].Value) == 2 {
+		value, err := strconv.ParseFloat(fmt.Sprint(data.Result[0].Value[1]), 64)
+		if err != nil {
+			return nil, err
+		}
+		count = uint64(value)
+	}
+	return &count, nil
+}
+
+func (p PrometheusFormatProvider) GetRecoveryPoint(testName string) (*RecoveryPoint, error) {
+	return p.state.recoveryPoint, nil
+}
+
+// api calls the HTTP API of the restored prometheus with wget inside the container
+func (p PrometheusFormatProvider) api(testName string, path string, params url.Values, data interface{}) error {
+	output, err := p.runtimeProvider.Exec(testName, "wget", "-q", "-O", "-", "http://"+prometheusRestoreAddress+path+"?"+params.Encode())
+	if err != nil {
+		return err
+	}
+
+	response := PrometheusResponse{}
+	err = json.Unmarshal([]byte(*output), &response)
+	if err != nil {
+		return err
+	}
+	if response.Status != "success" {
+		return fmt.Errorf("[%s] prometheus query failed: %s", testName, response.Error)
+	}
+	return json.Unmarshal(response.Data, data)
+}
+
+func NewPrometheusFormatProvider(runtimeProvider runtime.RuntimeProvider) PrometheusFormatProvider {
+	prometheusFormatProvider := PrometheusFormatProvider{
+		runtimeProvider: runtimeProvider,
+		state:           &prometheusState{},
+	}
+	return prometheusFormatProvider
+}
@@ -0,0 +1,217 @@
+package format
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/runtime"
+)
+
+const (
+	redisRestorePort    = "6380"
+	redisRestoreDir     = "/tmp/redis-restore"
+	redisRestoreTimeout = time.Hour
+)
+
+type RedisFormatProvider struct {
+	runtimeProvider runtime.RuntimeProvider
+}
+
+func (p RedisFormatProvider) Setup(testName string, dir string) error {
+	return p.runtimeProvider.Setup(testName, dir)
+}
+
+func (p RedisFormatProvider) Destroy(testName string, dir string) error {
+	return p.runtimeProvider.Destroy(testName, dir)
+}
+
+func (p RedisFormatProvider) GetRuntimeProvider() runtime.RuntimeProvider {
+	return p.runtimeProvider
+}
+
+// ImportData starts a separate redis-server on the restored dump.rdb or appendonly.aof file and waits until it is loaded
+func (p RedisFormatProvider) ImportData(testName string, dir string, options []string) error {
+	if len(options) == 0 || strings.HasPrefix(options[len(options)-1], "-") {
+		return fmt.Errorf("[%s] the last import option should be the restored dump.rdb or appendonly.aof file", testName)
+	}
+	file := options[len(options)-1]
+	args := []string{"--port", redisRestorePort, "--bind", "127.0.0.1", "--dir", redisRestoreDir, "--save", "", "--daemonize", "yes",
+		"--logfile", redisRestoreDir + ".log", "--pidfile", redisRestoreDir + ".pid"}
+
+	// Copy the file into the data dir of the new redis-server
+	var target string
+	if strings.HasSuffix(file, ".aof") {
+		target = "appendonly.aof"
+		args = append(args, "--appendonly", "yes", "--appendfilename", "appendonly.aof")
+	} else if path.Base(strings.TrimSuffix(file, "/")) == "appendonlydir" {
+		target = "appendonlydir"
+		args = append(args, "--appendonly", "yes", "--appenddirname", "appendonlydir")
+	} else {
+		target = "dump.rdb"
+		args = append(args, "--appendonly", "no", "--dbfilename", "dump.rdb")
+	}
+	args = append(args, options[:len(options)-1]...)
+
+	log.Printf("[%s] Loading %s into redis", testName, file)
+	_, err := p.runtimeProvider.Exec(testName, "sh", "-c", "rm -rf \""+redisRestoreDir+"\" && mkdir -p \""+redisRestoreDir+"\" && cp -r \"$1\" \""+redisRestoreDir+"/"+target+"\"", "sh", file)
+	if err != nil {
+		return err
+	}
+	_, err = p.runtimeProvider.Exec(testName, "redis-server", args...)
+	if err != nil {
+		return err
+	}
+
+	// Wait for redis to load the data
+	deadline := time.Now().Add(redisRestoreTimeout)
+	for {
+		output, err := p.redisCli(testName, "", "INFO", "persistence")
+		if err == nil && strings.Contains(*output, "loading:0") {
+			break
+		}
+		_, statusErr := p.runtimeProvider.Exec(testName, "sh", "-c", "kill -0 $(cat \""+redisRestoreDir+".pid\")")
+		if statusErr != nil {
+			logs, _ := p.runtimeProvider.Exec(testName, "tail", "-n", "20", redisRestoreDir+".log")
+			if logs != nil {
+				log.Printf("[%s] %s", testName, *logs)
+			}
+			return fmt.Errorf("[%s] redis stopped while loading %s", testName, file)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("[%s] redis didn't load %s within %s", testName, file, redisRestoreTimeout)
+		}
+		time.Sleep(time.Second)
+	}
+	log.Printf("[%s] Redis loaded %s", testName, file)
+	return nil
+}
+
+// ListDatabases returns the logical databases that contain keys, eg. db0
+func (p RedisFormatProvider) ListDatabases(testName string) ([]string, error) {
+	keyspace, err := p.getKeyspace(testName)
+	if err != nil {
+		return nil, err
+	}
+
+	databaseNames := []string{}
+	for database := range keyspace {
+		databaseNames = append(databaseNames, database)
+	}
+	return databaseNames, nil
+}
+
+func (p RedisFormatProvider) GetDatabaseSize(testName string, database string) (*uint64, error) {
+	return nil, fmt.Errorf(`[%s] GetDatabaseSize not available for redis format`, testName)
+}
+
+func (p RedisFormatProvider) ListTables(testName string, database string) ([]string, error) {
+	return nil, fmt.Errorf(`[%s] ListTables not available for redis format`, testName)
+}
+
+// QueryRecord returns the value of the key in the query
+func (p RedisFormatProvider) QueryRecord(testName string, database string, query string) (map[string]interface{}, error) {
+	db := strings.TrimPrefix(database, "db")
+	output, err := p.redisCli(testName, db, "TYPE", query)
+	if err != nil {
+		return nil, err
+	}
+
+	keyType := strings.TrimSpace(*output)
+	switch keyType {
+	case "string":
+		output, err = p.redisCli(testName, db, "GET", query)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"value": strings.TrimSuffix(*output, "\n")}, nil
+	case "hash":
+		output, err = p.redisCli(testName, db, "HGETALL", query)
+		if err != nil {
+			return nil, err
+		}
+		lines := strings.Split(strings.TrimSuffix(*output, "\n"), "\n")
+		result := map[string]interface{}{}
+		for i := 0; i+1 < len(lines); i += 2 {
+			result[lines[i]] = lines[i+1]
+		}
+		return result, nil
+	case "list", "set", "zset":
+		command := map[string][]string{
+			"list": {"LRANGE", query, "0", "-1"},
+			"set":  {"SMEMBERS", query},
+			"zset": {"ZRANGE", query, "0", "-1"},
+		}[keyType]
+		output, err = p.redisCli(testName, db, command...)
+		if err != nil {
+			return nil, err
+		}
+		values := []interface{}{}
+		for _, value := range strings.Split(strings.TrimSuffix(*output, "\n"), "\n") {
+			values = append(values, value)
+		}
+		return map[string]interface{}{"value": values}, nil
+	case "none":
+		return nil, fmt.Errorf("[%s] key %s not found in %s", testName, query, database)
+	}
+	return nil, fmt.Errorf("[%s] QueryRecord not supported for redis type %s", testName, keyType)
+}
+
+// CountRecords returns the amount of keys in a logical database
+func (p RedisFormatProvider) CountRecords(testName string, database string, table string, estimate bool) (*uint64, error) {
+	keyspace, err := p.getKeyspace(testName)
+	if err != nil {
+		return nil, err
+	}
+	keys, ok := keyspace[database]
+	if !ok {
+		return nil, fmt.Errorf("database %s not found", database)
+	}
+	return &keys, nil
+}
+
+// getKeyspace returns the amount of keys per logical database
+func (p RedisFormatProvider) getKeyspace(testName string) (map[string]uint64, error) {
+	output, err := p.redisCli(testName, "", "INFO", "keyspace")
+	if err != nil {
+		return nil, err
+	}
+
+	// eg. db0:keys=10,expires=0,avg_ttl=0
+	keyspace := map[string]uint64{}
+	for _, line := range strings.Split(*output, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 2)
+		if len(parts) != 2 || !strings.HasPrefix(parts[0], "db") {
+			continue
+		}
+		for _, field := range strings.Split(parts[1], ",") {
+			if strings.HasPrefix(field, "keys=") {
+				keys, err := strconv.ParseUint(strings.TrimPrefix(field, "keys="), 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				keyspace[parts[0]] = keys
+			}
+		}
+	}
+	return keyspace, nil
+}
+
+// redisCli runs a command on the restored redis-server, without a database no SELECT is done which is refused while loading
+func (p RedisFormatProvider) redisCli(testName string, database string, args ...string) (*string, error) {
+	cliArgs := []string{"-p", redisRestorePort}
+	if database != "" {
+		cliArgs = append(cliArgs, "-n", database)
+	}
+	return p.runtimeProvider.Exec(testName, "redis-cli", append(cliArgs, args...)...)
+}
+
+func NewRedisFormatProvider(runtimeProvider runtime.RuntimeProvider) RedisFormatProvider {
+	redisFormatProvider := RedisFormatProvider{
+		runtimeProvider: runtimeProvider,
+	}
+	return redisFormatProvider
+}
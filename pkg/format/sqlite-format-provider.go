@@ -0,0 +1,181 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type SqliteFormatProvider struct {
+	config SqliteConfig
+	state  *sqliteState
+}
+
+type sqliteState struct {
+	dir string
+}
+
+func (p SqliteFormatProvider) Setup(testName string, dir string) error {
+	// No container required, the databases are opened from the workdir
+	p.state.dir = dir
+	return nil
+}
+
+func (p SqliteFormatProvider) Destroy(testName string, dir string) error {
+	return nil
+}
+
+// ImportData runs an integrity check on every database, which catches databases that were copied during a write
+func (p SqliteFormatProvider) ImportData(testName string, dir string, options []string) error {
+	databases, err := p.ListDatabases(testName)
+	if err != nil {
+		return err
+	}
+	if len(databases) == 0 {
+		return fmt.Errorf("[%s] no sqlite databases found matching: %s", testName, strings.Join(p.config.Databases, ", "))
+	}
+
+	corruptDatabases := []string{}
+	for _, database := range databases {
+		log.Printf("[%s] Check integrity of %s", testName, database)
+		output, err := p.sqlite(testName, database, "PRAGMA integrity_check;")
+		if err != nil {
+			corruptDatabases = append(corruptDatabases, fmt.Sprintf("%s (%s)", database, err))
+			continue
+		}
+		result := strings.TrimSpace(*output)
+		if result != "ok" {
+			corruptDatabases = append(corruptDatabases, fmt.Sprintf("%s (%s)", database, strings.ReplaceAll(result, "\n", "; ")))
+		}
+	}
+
+	if len(corruptDatabases) > 0 {
+		return fmt.Errorf("[%s] integrity check failed for: %s", testName, strings.Join(corruptDatabases, ", "))
+	}
+	return nil
+}
+
+// ListDatabases returns the files matching the database glob patterns, relative to the workdir
+func (p SqliteFormatProvider) ListDatabases(testName string) ([]string, error) {
+	workDir := filepath.Join(p.state.dir, "workdir")
+	databaseNames := []string{}
+	for _, pattern := range p.config.Databases {
+		matchingFiles, err := filepath.Glob(filepath.Join(workDir, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid glob pattern: %s", pattern)
+		}
+		for _, file := range matchingFiles {
+			database, err := filepath.Rel(workDir, file)
+			if err != nil {
+				return nil, err
+			}
+			databaseNames = append(databaseNames, database)
+		}
+	}
+	return databaseNames, nil
+}
+
+func (p SqliteFormatProvider) GetDatabaseSize(testName string, database string) (*uint64, error) {
+	stats, err := os.Stat(p.databaseFile(database))
+	if err != nil {
+		return nil, err
+	}
+	size := uint64(stats.Size())
+
+	// Data that isn't checkpointed yet lives in the write-ahead log
+	walStats, err := os.Stat(p.databaseFile(database) + "-wal")
+	if err == nil {
+		size += uint64(walStats.Size())
+	}
+	return &size, nil
+}
+
+func (p SqliteFormatProvider) ListTables(testName string, database string) ([]string, error) {
+	output, err := p.sqlite(testName, database, "SELECT name FROM sqlite_master WHERE type='table' AND name NOT LIKE 'sqlite_%';")
+	if err != nil {
+		return nil, err
+	}
+
+	tableNames := []string{}
+	for _, table := range strings.Split(*output, "\n") {
+		tableName := strings.TrimSpace(table)
+		if tableName != "" {
+			tableNames = append(tableNames, tableName)
+		}
+	}
+	return tableNames, nil
+}
+
+func (p SqliteFormatProvider) QueryRecord(testName string, database string, query string) (map[string]interface{}, error) {
+	output, err := p.sqlite(testName, database, "-json", query)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(*output) == "" {
+		return nil, fmt.Errorf("[%s] query returned no records", testName)
+	}
+
+	result := []map[string]interface{}{}
+	err = json.Unmarshal([]byte(*output), &result)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("[%s] query returned no records", testName)
+	}
+	return result[0], nil
+}
+
+func (p SqliteFormatProvider) CountRecords(testName string, database string, table string, estimate bool) (*uint64, error) {
+	if table == "" {
+		return nil, fmt.Errorf("[%s] Counting records of the sqlite format needs a table, set 'tables' in the rowCount assert", testName)
+	}
+	output, err := p.sqlite(testName, database, "SELECT count(*) FROM \""+strings.ReplaceAll(table, "\"", "\"\"")+"\";")
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.ParseUint(strings.TrimSpace(*output), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &count, nil
+}
+
+func (p SqliteFormatProvider) databaseFile(database string) string {
+	return filepath.Join(p.state.dir, "workdir", database)
+}
+
+// sqlite runs the sqlite3 CLI on the host, the last argument is the SQL statement
+func (p SqliteFormatProvider) sqlite(testName string, database string, args ...string) (*string, error) {
+	options := args[:len(args)-1]
+	statement := args[len(args)-1]
+	cmd := exec.Command("sqlite3", append(append([]string{"-batch", "-bail", "-readonly"}, options...), p.databaseFile(database), statement)...)
+
+	var outb, errb bytes.Buffer
+	cmd.Stdout = &outb
+	cmd.Stderr = &errb
+	err := cmd.Run()
+	if err != nil {
+		errOutput := strings.TrimSpace(errb.String())
+		if errOutput != "" {
+			return nil, fmt.Errorf("%s", errOutput)
+		}
+		return nil, err
+	}
+	output := outb.String()
+	return &output, nil
+}
+
+func NewSqliteFormatProvider(config SqliteConfig) SqliteFormatProvider {
+	sqliteFormatProvider := SqliteFormatProvider{
+		config: config,
+		state:  &sqliteState{},
+	}
+	return sqliteFormatProvider
+}
@@ -0,0 +1,354 @@
+package format
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/runtime"
+)
+
+const (
+	vaultRestoreAddress = "http://127.0.0.1:8300"
+	vaultRestoreDir     = "/tmp/vault-restore"
+	vaultRestoreTimeout = 5 * time.Minute
+	vaultUnsealKeyEnv   = "BACKUP_VALIDATOR_VAULT_UNSEAL_KEY"
+	vaultRestoreConfig  = `storage "raft" {
+  path    = "` + vaultRestoreDir + `"
+  node_id = "backup-validator"
+}
+listener "tcp" {
+  address         = "127.0.0.1:8300"
+  cluster_address = "127.0.0.1:8301"
+  tls_disable     = true
+}
+api_addr      = "` + vaultRestoreAddress + `"
+cluster_addr  = "http://127.0.0.1:8301"
+disable_mlock = true
+`
+)
+
+type VaultRaftFormatProvider struct {
+	runtimeProvider runtime.RuntimeProvider
+	config          VaultConfig
+}
+
+type VaultStatus struct {
+	Initialized bool   `json:"initialized"`
+	Sealed      bool   `json:"sealed"`
+	HaEnabled   bool   `json:"ha_enabled"`
+	HaMode      string `json:"ha_mode"`
+}
+
+type VaultInitResult struct {
+	UnsealKeys []string `json:"unseal_keys_b64"`
+	RootToken  string   `json:"root_token"`
+}
+
+type VaultMount struct {
+	Type    string            `json:"type"`
+	Options map[string]string `json:"options"`
+}
+
+type VaultReadResult struct {
+	Data map[string]interface{} `json:"data"`
+}
+
+func (p VaultRaftFormatProvider) Setup(testName string, dir string) error {
+	return p.runtimeProvider.Setup(testName, dir)
+}
+
+func (p VaultRaftFormatProvider) Destroy(testName string, dir string) error {
+	return p.runtimeProvider.Destroy(testName, dir)
+}
+
+func (p VaultRaftFormatProvider) GetRuntimeProvider() runtime.RuntimeProvider {
+	return p.runtimeProvider
+}
+
+// ImportData starts a new raft based vault server, restores the snapshot into it and unseals it with the keys of the backed up cluster
+func (p VaultRaftFormatProvider) ImportData(testName string, dir string, options []string) error {
+	if len(options) == 0 || strings.HasPrefix(options[len(options)-1], "-") {
+		return fmt.Errorf("[%s] the last import option should be the restored vault raft snapshot file", testName)
+	}
+	file := options[len(options)-1]
+	if len(p.config.UnsealKeys) == 0 {
+		return fmt.Errorf("[%s] vault.unsealKeys are required to unseal the restored snapshot", testName)
+	}
+
+	// Start a new vault server with raft storage
+	log.Printf("[%s] Start vault server", testName)
+	_, err := p.runtimeProvider.Exec(testName, "sh", "-c", "rm -rf \"$1\" && mkdir -p \"$1\" && printf '%s' \"$2\" > \"$1.hcl\" && (nohup vault server -config=\"$1.hcl\" > \"$1.log\" 2>&1 &)", "sh", vaultRestoreDir, vaultRestoreConfig)
+	if err != nil {
+		return err
+	}
+	_, err = p.waitForStatus(testName, func(status *VaultStatus) bool {
+		return !status.Initialized
+	})
+	if err != nil {
+		return err
+	}
+
+	// Initialize and unseal the new cluster, so the snapshot can be restored
+	output, err := p.vault(testName, nil, "operator", "init", "-key-shares=1", "-key-threshold=1", "-format=json")
+	if err != nil {
+		return err
+	}
+	initResult := VaultInitResult{}
+	err = json.Unmarshal([]byte(*output), &initResult)
+	if err != nil {
+		return err
+	}
+	if len(initResult.UnsealKeys) == 0 {
+		return fmt.Errorf("[%s] vault operator init returned no unseal keys", testName)
+	}
+	err = p.unseal(testName, initResult.UnsealKeys[0])
+	if err != nil {
+		return err
+	}
+	_, err = p.waitForStatus(testName, vaultIsActive)
+	if err != nil {
+		return err
+	}
+
+	// Restore the snapshot, which seals vault with the keys of the backed up cluster
+	log.Printf("[%s] Restore snapshot %s", testName, file)
+	args := append([]string{"operator", "raft", "snapshot", "restore", "-force"}, options[:len(options)-1]...)
+	_, err = p.vault(testName, &initResult.RootToken, append(args, file)...)
+	if err != nil {
+		return err
+	}
+
+	// Unseal with the keys of the backed up cluster
+	log.Printf("[%s] Unseal restored vault", testName)
+	for _, unsealKey := range p.config.UnsealKeys {
+		key, err := unsealKey.Get()
+		if err != nil {
+			return err
+		}
+		err = p.unseal(testName, strings.TrimSpace(key))
+		if err != nil {
+			return err
+		}
+	}
+	_, err = p.waitForStatus(testName, vaultIsActive)
+	if err != nil {
+		return err
+	}
+	log.Printf("[%s] Vault restored and unsealed", testName)
+	return nil
+}
+
+// ListDatabases returns the mount paths of the secret engines, eg. secret/
+func (p VaultRaftFormatProvider) ListDatabases(testName string) ([]string, error) {
+	mounts, err := p.getMounts(testName)
+	if err != nil {
+		return nil, err
+	}
+
+	databaseNames := []string{}
+	for mount := range mounts {
+		databaseNames = append(databaseNames, mount)
+	}
+	sort.Strings(databaseNames)
+	return databaseNames, nil
+}
+
+func (p VaultRaftFormatProvider) GetDatabaseSize(testName string, database string) (*uint64, error) {
+	return nil, fmt.Errorf(`[%s] GetDatabaseSize not available for vault-raft format`, testName)
+}
+
+func (p VaultRaftFormatProvider) ListTables(testName string, database string) ([]string, error) {
+	return nil, fmt.Errorf(`[%s] ListTables not available for vault-raft format`, testName)
+}
+
+// QueryRecord reads the secret at the path in the query
+func (p VaultRaftFormatProvider) QueryRecord(testName string, database string, query string) (map[string]interface{}, error) {
+	token, err := p.getToken()
+	if err != nil {
+		return nil, err
+	}
+	output, err := p.vault(testName, token, "read", "-format=json", query)
+	if err != nil {
+		return nil, err
+	}
+
+	result := VaultReadResult{}
+	err = json.Unmarshal([]byte(*output), &result)
+	if err != nil {
+		return nil, err
+	}
+	return result.Data, nil
+}
+
+// CountRecords returns the amount of secrets in a mount, or certificates for pki mounts
+func (p VaultRaftFormatProvider) CountRecords(testName string, database string, table string, estimate bool) (*uint64, error) {
+	mounts, err := p.getMounts(testName)
+	if err != nil {
+		return nil, err
+	}
+	mount, ok := mounts[database]
+	if !ok {
+		return nil, fmt.Errorf("mount %s not found", database)
+	}
+
+	var count uint64
+	switch mount.Type {
+	case "kv":
+		count, err = p.countSecrets(testName, []string{"kv", "list"}, database)
+	case "pki":
+		count, err = p.countSecrets(testName, []string{"list"}, database+"certs")
+	default:
+		count, err = p.countSecrets(testName, []string{"list"}, database)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &count, nil
+}
+
+// countSecrets lists the path recursively and counts the entries that aren't folders
+func (p VaultRaftFormatProvider) countSecrets(testName string, listCommand []string, path string) (uint64, error) {
+	token, err := p.getToken()
+	if err != nil {
+		return 0, err
+	}
+	output, err := p.vault(testName, token, append(listCommand, "-format=json", path)...)
+	var execErr *runtime.ExecError
+	if errors.As(err, &execErr) && execErr.ExitCode == 2 {
+		// Nothing found at the path
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	keys := []string{}
+	err = json.Unmarshal([]byte(*output), &keys)
+	if err != nil {
+		return 0, err
+	}
+
+	count := uint64(0)
+	for _, key := range keys {
+		if strings.HasSuffix(key, "/") {
+			subCount, err := p.countSecrets(testName, listCommand, strings.TrimSuffix(path, "/")+"/"+key)
+			if err != nil {
+				return 0, err
+			}
+			count += subCount
+		} else {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (p VaultRaftFormatProvider) getMounts(testName string) (map[string]VaultMount, error) {
+	token, err := p.getToken()
+	if err != nil {
+		return nil, err
+	}
+	output, err := p.vault(testName, token, "secrets", "list", "-format=json")
+	if err != nil {
+		return nil, err
+	}
+
+	mounts := map[string]VaultMount{}
+	err = json.Unmarshal([]byte(*output), &mounts)
+	if err != nil {
+		return nil, err
+	}
+	return mounts, nil
+}
+
+func (p VaultRaftFormatProvider) getToken() (*string, error) {
+	if p.config.Token == nil {
+		return nil, fmt.Errorf("vault.token is required to read the restored vault")
+	}
+	token, err := p.config.Token.Get()
+	if err != nil {
+		return nil, err
+	}
+	token = strings.TrimSpace(token)
+	return &token, nil
+}
+
+// waitForStatus polls the status of the vault server until the condition is met
+func (p VaultRaftFormatProvider) waitForStatus(testName string, condition func(status *VaultStatus) bool) (*VaultStatus, error) {
+	deadline := time.Now().Add(vaultRestoreTimeout)
+	for {
+		// vault status exits with 2 when vault is sealed
+		output, err := p.vault(testName, nil, "status", "-format=json")
+		var execErr *runtime.ExecError
+		if errors.As(err, &execErr) && execErr.ExitCode == 2 {
+			output = &execErr.Stdout
+			err = nil
+		}
+		if err == nil {
+			status := &VaultStatus{}
+			err = json.Unmarshal([]byte(*output), status)
+			if err == nil && condition(status) {
+				return status, nil
+			}
+		}
+		if time.Now().After(deadline) {
+			logs, _ := p.runtimeProvider.Exec(testName, "tail", "-n", "20", vaultRestoreDir+".log")
+			if logs != nil {
+				log.Printf("[%s] %s", testName, *logs)
+			}
+			return nil, fmt.Errorf("[%s] vault didn't become ready within %s", testName, vaultRestoreTimeout)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// vault runs the vault cli, the token is passed in the environment so it isn't part of the docker command line or its errors
+func (p VaultRaftFormatProvider) vault(testName string, token *string, args ...string) (*string, error) {
+	env := map[string]string{"VAULT_ADDR": vaultRestoreAddress}
+	if token != nil {
+		env["VAULT_TOKEN"] = *token
+	}
+	return p.runtimeProvider.ExecWithEnv(testName, env, "vault", args...)
+}
+
+// unseal submits an unseal key, the key is passed in the environment and only expanded by the shell in the container
+func (p VaultRaftFormatProvider) unseal(testName string, key string) error {
+	env := map[string]string{
+		"VAULT_ADDR":      vaultRestoreAddress,
+		vaultUnsealKeyEnv: key,
+	}
+	_, err := p.runtimeProvider.ExecWithEnv(testName, env, "sh", "-c", "vault operator unseal \"$"+vaultUnsealKeyEnv+"\" > /dev/null")
+	return err
+}
+
+func vaultIsActive(status *VaultStatus) bool {
+	return status.Initialized && !status.Sealed && (!status.HaEnabled || status.HaMode == "active")
+}
+
+// Get returns the value, or reads it from the file
+func (v VaultValue) Get() (string, error) {
+	if v.Value != nil {
+		return *v.Value, nil
+	}
+	if v.FromFile != nil {
+		bytes, err := ioutil.ReadFile(*v.FromFile)
+		if err != nil {
+			return "", err
+		}
+		return string(bytes), nil
+	}
+	return "", fmt.Errorf("vault value doesn't has a 'value' or 'fromFile' field")
+}
+
+func NewVaultRaftFormatProvider(runtimeProvider runtime.RuntimeProvider, config VaultConfig) VaultRaftFormatProvider {
+	vaultRaftFormatProvider := VaultRaftFormatProvider{
+		runtimeProvider: runtimeProvider,
+		config:          config,
+	}
+	return vaultRaftFormatProvider
+}
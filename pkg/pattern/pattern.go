@@ -0,0 +1,188 @@
+package pattern
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Data is available in templates, eg. logs-{{ .SnapshotTime | addDays -1 | date "2006.01.02" }}
+type Data struct {
+	SnapshotTime time.Time
+	Now          time.Time
+	TestName     string
+}
+
+var funcs = template.FuncMap{
+	"addDays": func(days int, t time.Time) time.Time {
+		return t.AddDate(0, 0, days)
+	},
+	"addHours": func(hours int, t time.Time) time.Time {
+		return t.Add(time.Duration(hours) * time.Hour)
+	},
+	"date": func(layout string, t time.Time) string {
+		return t.Format(layout)
+	},
+	"utc": func(t time.Time) time.Time {
+		return t.UTC()
+	},
+}
+
+func NewData(testName string, snapshotTime time.Time) Data {
+	return Data{
+		SnapshotTime: snapshotTime,
+		Now:          time.Now(),
+		TestName:     testName,
+	}
+}
+
+// Render executes the text as template, text without '{{' is returned as is
+func Render(text string, data Data) (string, error) {
+	if !strings.Contains(text, "{{") {
+		return text, nil
+	}
+	tmpl, err := template.New("pattern").Funcs(funcs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid template '%s': %s (write {{\"{{\"}} for a literal {{)", text, err)
+	}
+	var buffer bytes.Buffer
+	err = tmpl.Execute(&buffer, data)
+	if err != nil {
+		return "", fmt.Errorf("invalid template '%s': %s (write {{\"{{\"}} for a literal {{)", text, err)
+	}
+	return buffer.String(), nil
+}
+
+// RenderConfig renders all strings in the config, config should be a pointer. The rendered strings are copied, so the original values are kept.
+func RenderConfig(config interface{}, data Data) error {
+	value := reflect.ValueOf(config)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return fmt.Errorf("config should be a pointer")
+	}
+	rendered, err := renderValue(value.Elem(), data)
+	if err != nil {
+		return err
+	}
+	value.Elem().Set(rendered)
+	return nil
+}
+
+func renderValue(value reflect.Value, data Data) (reflect.Value, error) {
+	switch value.Kind() {
+	case reflect.String:
+		text, err := Render(value.String(), data)
+		if err != nil {
+			return value, err
+		}
+		rendered := reflect.New(value.Type()).Elem()
+		rendered.SetString(text)
+		return rendered, nil
+	case reflect.Ptr:
+		if value.IsNil() {
+			return value, nil
+		}
+		elem, err := renderValue(value.Elem(), data)
+		if err != nil {
+			return value, err
+		}
+		rendered := reflect.New(value.Type().Elem())
+		rendered.Elem().Set(elem)
+		return rendered, nil
+	case reflect.Interface:
+		if value.IsNil() {
+			return value, nil
+		}
+		elem, err := renderValue(value.Elem(), data)
+		if err != nil {
+			return value, err
+		}
+		rendered := reflect.New(value.Type()).Elem()
+		rendered.Set(elem)
+		return rendered, nil
+	case reflect.Struct:
+		rendered := reflect.New(value.Type()).Elem()
+		rendered.Set(value)
+		for i := 0; i < value.NumField(); i++ {
+			if !rendered.Field(i).CanSet() {
+				continue
+			}
+			field, err := renderValue(value.Field(i), data)
+			if err != nil {
+				return value, err
+			}
+			rendered.Field(i).Set(field)
+		}
+		return rendered, nil
+	case reflect.Slice:
+		if value.IsNil() {
+			return value, nil
+		}
+		rendered := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			elem, err := renderValue(value.Index(i), data)
+			if err != nil {
+				return value, err
+			}
+			rendered.Index(i).Set(elem)
+		}
+		return rendered, nil
+	case reflect.Map:
+		if value.IsNil() {
+			return value, nil
+		}
+		rendered := reflect.MakeMapWithSize(value.Type(), value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			elem, err := renderValue(iter.Value(), data)
+			if err != nil {
+				return value, err
+			}
+			rendered.SetMapIndex(iter.Key(), elem)
+		}
+		return rendered, nil
+	}
+	return value, nil
+}
+
+// Compile returns the regex of a pattern, which is a regex between slashes (eg. /^logs-[0-9]+$/) or a name with '*' wildcards.
+// A name with wildcards has to match the whole name, so logs-* doesn't match restored-logs-1.
+func Compile(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		regex, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid regex %s: %s", pattern, err)
+		}
+		return regex, nil
+	}
+
+	parts := strings.Split(pattern, "*")
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	regexStr := "^" + strings.Join(parts, ".*") + "$"
+	regex, err := regexp.Compile(regexStr)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid regex: " + regexStr)
+	}
+	return regex, nil
+}
+
+// Match returns the names that match the pattern
+func Match(pattern string, names []string) ([]string, error) {
+	regex, err := Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	matchingNames := []string{}
+	for _, name := range names {
+		if regex.MatchString(name) {
+			matchingNames = append(matchingNames, name)
+		}
+	}
+	return matchingNames, nil
+}
@@ -0,0 +1,97 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+)
+
+// BackupProvider forwards the calls of a backup provider to a plugin
+type BackupProvider struct {
+	plugin *Plugin
+}
+
+type SnapshotMessage struct {
+	Name      string   `json:"name"`
+	Time      string   `json:"time"`
+	Databases []string `json:"databases"`
+}
+
+type RestoreParams struct {
+	Dir           string          `json:"dir"`
+	Snapshot      SnapshotMessage `json:"snapshot"`
+	ImportOptions []string        `json:"importOptions"`
+}
+
+type RestoreResult struct {
+	RestoredDatabases []string `json:"restoredDatabases"`
+}
+
+func (p BackupProvider) ListSnapshots(testName string, dir string) ([]*backup.Snapshot, error) {
+	messages := []SnapshotMessage{}
+	err := p.plugin.Call("listSnapshots", DirParams{Dir: dir}, &messages)
+	if err != nil {
+		return nil, err
+	}
+	snapshots := []*backup.Snapshot{}
+	for _, message := range messages {
+		snapshot, err := message.toSnapshot()
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+func (p BackupProvider) Restore(testName string, dir string, snapshot *backup.Snapshot, importOptions []string) error {
+	result := RestoreResult{}
+	err := p.plugin.Call("restore", RestoreParams{
+		Dir:           dir,
+		Snapshot:      NewSnapshotMessage(snapshot),
+		ImportOptions: importOptions,
+	}, &result)
+	if err != nil {
+		return err
+	}
+	snapshot.RestoredDatabases = result.RestoredDatabases
+	return nil
+}
+
+// NewSnapshotMessage converts a snapshot to the message sent to plugins, the time is formatted as RFC3339
+func NewSnapshotMessage(snapshot *backup.Snapshot) SnapshotMessage {
+	return SnapshotMessage{
+		Name:      snapshot.Name,
+		Time:      snapshot.Time.Format(time.RFC3339),
+		Databases: snapshot.Databases,
+	}
+}
+
+func (m SnapshotMessage) toSnapshot() (*backup.Snapshot, error) {
+	snapshotTime, err := time.Parse(time.RFC3339, m.Time)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time of snapshot %s: %s", m.Name, err)
+	}
+	return &backup.Snapshot{
+		Name:      m.Name,
+		Time:      snapshotTime,
+		Databases: m.Databases,
+	}, nil
+}
+
+// Close stops the plugin
+func (p BackupProvider) Close() error {
+	return p.plugin.Close()
+}
+
+func NewBackupProvider(testName string, config Config) (*BackupProvider, error) {
+	plugin, err := Start("backup", testName, config)
+	if err != nil {
+		return nil, err
+	}
+	backupProvider := &BackupProvider{
+		plugin: plugin,
+	}
+	return backupProvider, nil
+}
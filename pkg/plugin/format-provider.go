@@ -0,0 +1,103 @@
+package plugin
+
+// FormatProvider forwards the calls of a format to a plugin
+type FormatProvider struct {
+	plugin *Plugin
+}
+
+type DirParams struct {
+	Dir string `json:"dir"`
+}
+
+type ImportDataParams struct {
+	Dir     string   `json:"dir"`
+	Options []string `json:"options"`
+}
+
+type DatabaseParams struct {
+	Database string `json:"database"`
+}
+
+type QueryRecordParams struct {
+	Database string `json:"database"`
+	Query    string `json:"query"`
+}
+
+type CountRecordsParams struct {
+	Database string `json:"database"`
+	Table    string `json:"table"`
+	Estimate bool   `json:"estimate"`
+}
+
+func (p FormatProvider) Setup(testName string, dir string) error {
+	return p.plugin.Call("setup", DirParams{Dir: dir}, nil)
+}
+
+func (p FormatProvider) Destroy(testName string, dir string) error {
+	return p.plugin.Call("destroy", DirParams{Dir: dir}, nil)
+}
+
+func (p FormatProvider) ImportData(testName string, dir string, options []string) error {
+	return p.plugin.Call("importData", ImportDataParams{Dir: dir, Options: options}, nil)
+}
+
+func (p FormatProvider) ListDatabases(testName string) ([]string, error) {
+	databases := []string{}
+	err := p.plugin.Call("listDatabases", struct{}{}, &databases)
+	if err != nil {
+		return nil, err
+	}
+	return databases, nil
+}
+
+func (p FormatProvider) GetDatabaseSize(testName string, database string) (*uint64, error) {
+	var size uint64
+	err := p.plugin.Call("getDatabaseSize", DatabaseParams{Database: database}, &size)
+	if err != nil {
+		return nil, err
+	}
+	return &size, nil
+}
+
+func (p FormatProvider) ListTables(testName string, database string) ([]string, error) {
+	tables := []string{}
+	err := p.plugin.Call("listTables", DatabaseParams{Database: database}, &tables)
+	if err != nil {
+		return nil, err
+	}
+	return tables, nil
+}
+
+func (p FormatProvider) QueryRecord(testName string, database string, query string) (map[string]interface{}, error) {
+	record := map[string]interface{}{}
+	err := p.plugin.Call("queryRecord", QueryRecordParams{Database: database, Query: query}, &record)
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (p FormatProvider) CountRecords(testName string, database string, table string, estimate bool) (*uint64, error) {
+	var count uint64
+	err := p.plugin.Call("countRecords", CountRecordsParams{Database: database, Table: table, Estimate: estimate}, &count)
+	if err != nil {
+		return nil, err
+	}
+	return &count, nil
+}
+
+// Close stops the plugin
+func (p FormatProvider) Close() error {
+	return p.plugin.Close()
+}
+
+func NewFormatProvider(testName string, config Config) (*FormatProvider, error) {
+	plugin, err := Start("format", testName, config)
+	if err != nil {
+		return nil, err
+	}
+	formatProvider := &FormatProvider{
+		plugin: plugin,
+	}
+	return formatProvider, nil
+}
@@ -0,0 +1,203 @@
+package plugin
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+)
+
+// TestPlugin runs the requests of a kind of plugin the way the validator does, so plugin authors can test their plugin without a test definition.
+// The responses are checked against the protocol, every problem is listed in the returned error.
+func TestPlugin(kind string, config Config, dir string) error {
+	testName := "plugin-test"
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	switch kind {
+	case "assert":
+		problems, err = testAssertPlugin(testName, config, absDir)
+	case "backup":
+		problems, err = testBackupPlugin(testName, config, absDir)
+	case "format":
+		problems, err = testFormatPlugin(testName, config, absDir)
+	default:
+		return fmt.Errorf("Unsupported plugin kind '%s', should be one of: assert, format or backup", kind)
+	}
+	if err != nil {
+		return err
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("[%s] plugin %s doesn't follow the protocol:\n- %s", testName, config.Name, strings.Join(problems, "\n- "))
+	}
+	log.Printf("[%s] All checks passed", testName)
+	return nil
+}
+
+func testAssertPlugin(testName string, config Config, dir string) ([]string, error) {
+	assertPlugin, err := Start("assert", testName, config)
+	if err != nil {
+		return nil, err
+	}
+	defer assertPlugin.Close()
+	result := RunResult{}
+	err = assertPlugin.Call("run", RunParams{
+		Dir:      dir,
+		Snapshot: NewSnapshotMessage(&backup.Snapshot{Name: "plugin-test", Time: time.Now()}),
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	status := "pass"
+	if result.Status != nil {
+		status = *result.Status
+	} else if result.Message != nil {
+		status = "fail"
+	}
+	message := ""
+	if result.Message != nil {
+		message = *result.Message
+	}
+	log.Printf("[%s] run: %s %s", testName, status, message)
+	return checkRunResult(result), nil
+}
+
+// checkRunResult checks the statuses of the result and its details
+func checkRunResult(result RunResult) []string {
+	problems := []string{}
+	if result.Status != nil && !isRunStatus(*result.Status) {
+		problems = append(problems, fmt.Sprintf("run: unsupported status '%s', should be one of: %s", *result.Status, strings.Join(RunStatuses, ", ")))
+	}
+	for i, detail := range result.Details {
+		if detail.Database == "" {
+			problems = append(problems, fmt.Sprintf("run: detail %d has no database", i))
+		}
+		if !isRunStatus(detail.Status) {
+			problems = append(problems, fmt.Sprintf("run: detail %d has unsupported status '%s', should be one of: %s", i, detail.Status, strings.Join(RunStatuses, ", ")))
+		}
+	}
+	return problems
+}
+
+func isRunStatus(status string) bool {
+	for _, runStatus := range RunStatuses {
+		if status == runStatus {
+			return true
+		}
+	}
+	return false
+}
+
+func testBackupPlugin(testName string, config Config, dir string) ([]string, error) {
+	backupProvider, err := NewBackupProvider(testName, config)
+	if err != nil {
+		return nil, err
+	}
+	defer backupProvider.Close()
+	snapshots, err := backupProvider.ListSnapshots(testName, dir)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[%s] listSnapshots: %d snapshots", testName, len(snapshots))
+
+	names := []string{}
+	for i, snapshot := range snapshots {
+		names = append(names, snapshot.Name)
+		if i > 0 && snapshot.Time.Before(snapshots[i-1].Time) {
+			return nil, fmt.Errorf("[%s] listSnapshots: snapshots should be listed from old to new, %s is older than %s", testName, snapshot.Name, snapshots[i-1].Name)
+		}
+	}
+	problems := checkNames("listSnapshots", "snapshot", names)
+	if len(snapshots) == 0 {
+		log.Printf("[%s] No snapshots, restore isn't tested", testName)
+		return problems, nil
+	}
+
+	snapshot := snapshots[len(snapshots)-1]
+	err = backupProvider.Restore(testName, dir, snapshot, []string{})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[%s] restore: restored snapshot %s of %s", testName, snapshot.Name, snapshot.Time.Format(time.RFC3339))
+	if _, err := os.Stat(filepath.Join(dir, "workdir")); err != nil {
+		log.Printf("[%s] restore: %s/workdir doesn't exist, formats read the restored files from there", testName, dir)
+	}
+	if snapshot.RestoredDatabases != nil {
+		problems = append(problems, checkNames("restore", "restored database", snapshot.RestoredDatabases)...)
+	}
+	return problems, nil
+}
+
+func testFormatPlugin(testName string, config Config, dir string) ([]string, error) {
+	formatProvider, err := NewFormatProvider(testName, config)
+	if err != nil {
+		return nil, err
+	}
+	defer formatProvider.Close()
+	err = formatProvider.Setup(testName, dir)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[%s] setup: done", testName)
+
+	problems, err := testFormatRequests(testName, formatProvider, dir)
+	destroyErr := formatProvider.Destroy(testName, dir)
+	if err != nil {
+		return nil, err
+	}
+	if destroyErr != nil {
+		return nil, destroyErr
+	}
+	log.Printf("[%s] destroy: done", testName)
+	return problems, nil
+}
+
+func testFormatRequests(testName string, formatProvider *FormatProvider, dir string) ([]string, error) {
+	err := formatProvider.ImportData(testName, dir, []string{})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[%s] importData: done", testName)
+	databases, err := formatProvider.ListDatabases(testName)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[%s] listDatabases: %v", testName, databases)
+	problems := checkNames("listDatabases", "database", databases)
+	for _, database := range databases {
+		size, err := formatProvider.GetDatabaseSize(testName, database)
+		if err != nil {
+			return nil, err
+		}
+		tables, err := formatProvider.ListTables(testName, database)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("[%s] %s: %d bytes, tables: %v", testName, database, *size, tables)
+		problems = append(problems, checkNames("listTables "+database, "table", tables)...)
+	}
+	return problems, nil
+}
+
+// checkNames reports empty and duplicate names
+func checkNames(method string, kind string, names []string) []string {
+	problems := []string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		if name == "" {
+			problems = append(problems, fmt.Sprintf("%s: empty %s name", method, kind))
+		} else if seen[name] {
+			problems = append(problems, fmt.Sprintf("%s: duplicate %s %s", method, kind, name))
+		}
+		seen[name] = true
+	}
+	return problems
+}
@@ -0,0 +1,255 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProtocolVersion is increased when the messages change in an incompatible way
+const ProtocolVersion = 1
+
+// ExecutablePrefix is the prefix of plugin executables that are looked up in the PATH, eg. backup-validator-plugin-cassandra
+const ExecutablePrefix = "backup-validator-plugin-"
+
+// DefaultTimeout is the max time a plugin may take to answer a request
+const DefaultTimeout = time.Hour
+
+// RunStatuses are the statuses an assert plugin may return
+var RunStatuses = []string{"pass", "fail", "warn", "skip", "error"}
+
+// Config references a plugin by name from the test definition
+type Config struct {
+	Name    string                 `yaml:"name"`
+	Config  map[string]interface{} `yaml:"config"`
+	Timeout *string                `yaml:"timeout"`
+}
+
+type Request struct {
+	ID     uint64      `json:"id"`
+	Method string      `json:"method"`
+	Params interface{} `json:"params"`
+}
+
+type Response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *string         `json:"error"`
+}
+
+type InitParams struct {
+	ProtocolVersion int                    `json:"protocolVersion"`
+	Kind            string                 `json:"kind"`
+	TestName        string                 `json:"testName"`
+	Config          map[string]interface{} `json:"config"`
+}
+
+type InitResult struct {
+	ProtocolVersion int `json:"protocolVersion"`
+}
+
+// Plugin is a running plugin executable, which answers one json request per line on stdin with one json response per line on stdout
+type Plugin struct {
+	name    string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  *bufio.Reader
+	timeout time.Duration
+	state   *pluginState
+}
+
+type pluginState struct {
+	mutex  sync.Mutex
+	nextID uint64
+	killed bool
+}
+
+// Start runs the plugin executable and initializes it for a kind of plugin: assert, format or backup
+func Start(kind string, testName string, config Config) (*Plugin, error) {
+	path, err := Find(config.Name)
+	if err != nil {
+		return nil, err
+	}
+	timeout := DefaultTimeout
+	if config.Timeout != nil {
+		timeout, err = time.ParseDuration(*config.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("[%s] Invalid timeout of plugin %s: %s", testName, config.Name, err)
+		}
+	}
+
+	cmd := exec.Command(path)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[%s] Start %s plugin %s", testName, kind, path)
+	err = cmd.Start()
+	if err != nil {
+		return nil, fmt.Errorf("[%s] Failed to start plugin %s: %s", testName, config.Name, err)
+	}
+
+	plugin := &Plugin{
+		name:    config.Name,
+		cmd:     cmd,
+		stdin:   stdin,
+		stdout:  bufio.NewReader(stdout),
+		timeout: timeout,
+		state:   &pluginState{},
+	}
+	pluginConfig := config.Config
+	if pluginConfig == nil {
+		pluginConfig = map[string]interface{}{}
+	}
+	result := InitResult{}
+	err = plugin.Call("init", InitParams{
+		ProtocolVersion: ProtocolVersion,
+		Kind:            kind,
+		TestName:        testName,
+		Config:          pluginConfig,
+	}, &result)
+	if err != nil {
+		plugin.Close()
+		return nil, err
+	}
+	if result.ProtocolVersion != ProtocolVersion {
+		plugin.Close()
+		return nil, fmt.Errorf("[%s] Plugin %s uses protocol version %d, expected %d", testName, config.Name, result.ProtocolVersion, ProtocolVersion)
+	}
+	return plugin, nil
+}
+
+// Find returns the path of the plugin executable, a name with a slash is used as path (eg. ./plugins/my-check)
+func Find(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("Missing plugin name")
+	}
+	if strings.Contains(name, "/") {
+		return name, nil
+	}
+	path, err := exec.LookPath(ExecutablePrefix + name)
+	if err != nil {
+		return "", fmt.Errorf("Plugin %s not found, add %s%s to the PATH", name, ExecutablePrefix, name)
+	}
+	return path, nil
+}
+
+// Call sends a request to the plugin and decodes the result of the response into result, the plugin is killed when it doesn't answer within the timeout
+func (p *Plugin) Call(method string, params interface{}, result interface{}) error {
+	p.state.mutex.Lock()
+	defer p.state.mutex.Unlock()
+	if p.state.killed {
+		return fmt.Errorf("plugin %s: %s failed: the plugin was killed after a timeout", p.name, method)
+	}
+
+	p.state.nextID++
+	request := Request{
+		ID:     p.state.nextID,
+		Method: method,
+		Params: params,
+	}
+	bytes, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	type exchange struct {
+		line []byte
+		err  error
+	}
+	done := make(chan exchange, 1)
+	go func() {
+		_, err := p.stdin.Write(append(bytes, '\n'))
+		if err != nil {
+			done <- exchange{err: err}
+			return
+		}
+		line, err := p.stdout.ReadBytes('\n')
+		done <- exchange{line: line, err: err}
+	}()
+
+	var line []byte
+	select {
+	case answer := <-done:
+		if answer.err != nil {
+			return fmt.Errorf("plugin %s: %s failed: %s", p.name, method, answer.err)
+		}
+		line = answer.line
+	case <-time.After(p.timeout):
+		p.state.killed = true
+		p.cmd.Process.Kill()
+		return fmt.Errorf("plugin %s: %s didn't answer within %s, the plugin is killed", p.name, method, p.timeout)
+	}
+
+	response := Response{}
+	err = json.Unmarshal(line, &response)
+	if err != nil {
+		return fmt.Errorf("plugin %s: invalid response to %s: %s", p.name, method, err)
+	}
+	if response.ID != request.ID {
+		return fmt.Errorf("plugin %s: response id %d doesn't match request id %d", p.name, response.ID, request.ID)
+	}
+	if response.Error != nil {
+		return fmt.Errorf("plugin %s: %s", p.name, *response.Error)
+	}
+	if result != nil && len(response.Result) > 0 {
+		err = json.Unmarshal(response.Result, result)
+		if err != nil {
+			return fmt.Errorf("plugin %s: invalid result of %s: %s", p.name, method, err)
+		}
+	}
+	return nil
+}
+
+// Close stops the plugin by closing stdin, it is killed when it doesn't exit within 10 seconds
+func (p *Plugin) Close() error {
+	p.stdin.Close()
+	done := make(chan error, 1)
+	go func() {
+		done <- p.cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(10 * time.Second):
+		p.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+type RunParams struct {
+	Dir            string          `json:"dir"`
+	Snapshot       SnapshotMessage `json:"snapshot"`
+	RestoreSeconds float64         `json:"restoreSeconds"`
+	ImportSeconds  float64         `json:"importSeconds"`
+	Databases      []string        `json:"databases"`
+}
+
+// RunResult is the outcome of an assert plugin, without a status the assert fails when there is a message
+type RunResult struct {
+	Status   *string         `json:"status"`
+	Message  *string         `json:"message"`
+	Expected string          `json:"expected"`
+	Actual   string          `json:"actual"`
+	Details  []DetailMessage `json:"details"`
+}
+
+type DetailMessage struct {
+	Database string `json:"database"`
+	Status   string `json:"status"`
+	Message  string `json:"message"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
@@ -0,0 +1,231 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPluginEnv makes the test binary serve testHandler, so it can be started as plugin
+const testPluginEnv = "BACKUP_VALIDATOR_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(testPluginEnv) == "1" {
+		err := Serve(&testHandler{})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Setenv(testPluginEnv, "1")
+	code := m.Run()
+	os.Unsetenv(testPluginEnv)
+	os.Exit(code)
+}
+
+// testHandler answers every method with the value of the method in the config, methods named in 'hang' never answer
+type testHandler struct {
+	config map[string]interface{}
+}
+
+func (h *testHandler) Init(params InitParams) error {
+	h.config = params.Config
+	return nil
+}
+
+func (h *testHandler) Handle(method string, params json.RawMessage) (interface{}, error) {
+	if h.config["hang"] == method {
+		time.Sleep(time.Hour)
+	}
+	result, ok := h.config[method]
+	if !ok {
+		return nil, fmt.Errorf("unknown method %s", method)
+	}
+	return result, nil
+}
+
+func testPluginConfig(config map[string]interface{}) Config {
+	return Config{Name: os.Args[0], Config: config}
+}
+
+func TestCall(t *testing.T) {
+	plugin, err := Start("assert", "test", testPluginConfig(map[string]interface{}{
+		"run": map[string]interface{}{"status": "warn", "message": "3 invoices without total"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Close()
+
+	result := RunResult{}
+	err = plugin.Call("run", RunParams{}, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status == nil || *result.Status != "warn" || result.Message == nil || *result.Message != "3 invoices without total" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	err = plugin.Call("listTables", DatabaseParams{Database: "shop"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown method listTables") {
+		t.Errorf("expected the error of the plugin, got %v", err)
+	}
+}
+
+func TestCallTimeout(t *testing.T) {
+	config := testPluginConfig(map[string]interface{}{"hang": "run"})
+	timeout := "200ms"
+	config.Timeout = &timeout
+	plugin, err := Start("assert", "test", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plugin.Close()
+
+	started := time.Now()
+	err = plugin.Call("run", RunParams{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "didn't answer within 200ms") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > 10*time.Second {
+		t.Errorf("the timeout took %s", elapsed)
+	}
+
+	err = plugin.Call("run", RunParams{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "killed") {
+		t.Errorf("expected the plugin to be killed, got %v", err)
+	}
+}
+
+func TestStartInvalidTimeout(t *testing.T) {
+	config := testPluginConfig(nil)
+	timeout := "an hour"
+	config.Timeout = &timeout
+	_, err := Start("assert", "test", config)
+	if err == nil || !strings.Contains(err.Error(), "Invalid timeout") {
+		t.Errorf("expected an invalid timeout, got %v", err)
+	}
+}
+
+func TestTestPlugin(t *testing.T) {
+	tests := []struct {
+		name     string
+		kind     string
+		config   map[string]interface{}
+		problems []string
+	}{
+		{
+			name: "assert",
+			kind: "assert",
+			config: map[string]interface{}{
+				"run": map[string]interface{}{
+					"status":  "fail",
+					"details": []interface{}{map[string]interface{}{"database": "shop", "status": "fail"}},
+				},
+			},
+		},
+		{
+			name: "assert unsupported status",
+			kind: "assert",
+			config: map[string]interface{}{
+				"run": map[string]interface{}{
+					"status":  "failed",
+					"details": []interface{}{map[string]interface{}{"status": "ok"}},
+				},
+			},
+			problems: []string{
+				"run: unsupported status 'failed'",
+				"run: detail 0 has no database",
+				"run: detail 0 has unsupported status 'ok'",
+			},
+		},
+		{
+			name: "backup",
+			kind: "backup",
+			config: map[string]interface{}{
+				"listSnapshots": []interface{}{
+					map[string]interface{}{"name": "nightly-1", "time": "2024-01-01T02:00:00Z"},
+					map[string]interface{}{"name": "nightly-2", "time": "2024-01-02T02:00:00Z"},
+				},
+				"restore": map[string]interface{}{"restoredDatabases": []string{"shop"}},
+			},
+		},
+		{
+			name: "backup duplicate snapshots",
+			kind: "backup",
+			config: map[string]interface{}{
+				"listSnapshots": []interface{}{
+					map[string]interface{}{"name": "nightly", "time": "2024-01-01T02:00:00Z"},
+					map[string]interface{}{"name": "nightly", "time": "2024-01-02T02:00:00Z"},
+				},
+				"restore": map[string]interface{}{"restoredDatabases": []string{"shop", ""}},
+			},
+			problems: []string{
+				"listSnapshots: duplicate snapshot nightly",
+				"restore: empty restored database name",
+			},
+		},
+		{
+			name: "backup unordered snapshots",
+			kind: "backup",
+			config: map[string]interface{}{
+				"listSnapshots": []interface{}{
+					map[string]interface{}{"name": "nightly-2", "time": "2024-01-02T02:00:00Z"},
+					map[string]interface{}{"name": "nightly-1", "time": "2024-01-01T02:00:00Z"},
+				},
+			},
+			problems: []string{"snapshots should be listed from old to new"},
+		},
+		{
+			name: "format",
+			kind: "format",
+			config: map[string]interface{}{
+				"setup":           nil,
+				"importData":      nil,
+				"destroy":         nil,
+				"listDatabases":   []string{"shop", "users"},
+				"getDatabaseSize": 1024,
+				"listTables":      []string{"orders", "invoices"},
+			},
+		},
+		{
+			name: "format duplicate databases",
+			kind: "format",
+			config: map[string]interface{}{
+				"setup":           nil,
+				"importData":      nil,
+				"destroy":         nil,
+				"listDatabases":   []string{"shop", "shop"},
+				"getDatabaseSize": 1024,
+				"listTables":      []string{"orders", ""},
+			},
+			problems: []string{
+				"listDatabases: duplicate database shop",
+				"listTables shop: empty table name",
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := TestPlugin(test.kind, testPluginConfig(test.config), t.TempDir())
+			if len(test.problems) == 0 {
+				if err != nil {
+					t.Errorf("expected no problems, got %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("expected problems %v", test.problems)
+			}
+			for _, problem := range test.problems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("expected problem %q, got %v", problem, err)
+				}
+			}
+		})
+	}
+}
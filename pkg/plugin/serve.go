@@ -0,0 +1,72 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Handler implements a plugin in Go, see Serve
+type Handler interface {
+	// Init is called once with the kind of plugin and the config from the test definition
+	Init(params InitParams) error
+	// Handle answers a request, the result is encoded as json
+	Handle(method string, params json.RawMessage) (interface{}, error)
+}
+
+type incomingRequest struct {
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type outgoingResponse struct {
+	ID     uint64      `json:"id"`
+	Result interface{} `json:"result"`
+	Error  *string     `json:"error,omitempty"`
+}
+
+// Serve answers the requests of the validator on stdin and stdout until stdin is closed, logs should be written to stderr
+func Serve(handler Handler) error {
+	return ServeIO(handler, os.Stdin, os.Stdout)
+}
+
+// ServeIO answers the requests read from reader on writer until reader is closed
+func ServeIO(handler Handler, reader io.Reader, writer io.Writer) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	encoder := json.NewEncoder(writer)
+	for scanner.Scan() {
+		request := incomingRequest{}
+		err := json.Unmarshal(scanner.Bytes(), &request)
+		if err != nil {
+			return fmt.Errorf("invalid request: %s", err)
+		}
+
+		var result interface{}
+		if request.Method == "init" {
+			params := InitParams{}
+			err = json.Unmarshal(request.Params, &params)
+			if err == nil {
+				err = handler.Init(params)
+			}
+			result = InitResult{ProtocolVersion: ProtocolVersion}
+		} else {
+			result, err = handler.Handle(request.Method, request.Params)
+		}
+
+		response := outgoingResponse{ID: request.ID, Result: result}
+		if err != nil {
+			msg := err.Error()
+			response.Error = &msg
+			response.Result = nil
+		}
+		err = encoder.Encode(response)
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
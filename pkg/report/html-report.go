@@ -35,16 +35,20 @@ var HTML_REPORT = `
           {{- else if and .FailedAsserts (gt (len .FailedAsserts) 0) }}
           <td style="text-align: left; padding: 10px; border: 1px solid #f6f6f7; background-color: #f44336; color: white" class="passed">
             Failed
-            <ul style="font-size: 12px; margin: 0; padding-left: 20px;">
-              {{- range .FailedAsserts }}
-              <li>{{ . }}</li>
-              {{- end }}
-            </ul>
+            {{- template "assertResults" . }}
+          </td>
+          {{- else if .Warnings }}
+          <td style="text-align: left; padding: 10px; border: 1px solid #f6f6f7; background-color: #ff9800; color: white" class="passed">
+            Passed with warnings
+            {{- template "assertResults" . }}
           </td>
           {{- else }}
-          <td style="text-align: left; padding: 10px; border: 1px solid #f6f6f7; background-color: #4caf50; color: white" class="passed">Passed</td>
+          <td style="text-align: left; padding: 10px; border: 1px solid #f6f6f7; background-color: #4caf50; color: white" class="passed">
+            Passed
+            {{- template "assertResults" . }}
+          </td>
           {{- end }}
-          <td style="text-align: left; padding: 10px; border: 1px solid #f6f6f7; font-size: 12px; min-width: 150px;">total: {{ .TotalDuration }}<br>(restore: {{ .RestoreDuration }}, import: {{ .ImportDuration }})</td>
+          <td style="text-align: left; padding: 10px; border: 1px solid #f6f6f7; font-size: 12px; min-width: 150px;">total: {{ .TotalDuration }}<br>(restore: {{ .RestoreDuration }}, import: {{ .ImportDuration }}){{ if .RecoveryPoint }}<br>recovered until: {{ .RecoveryPoint }}{{ end }}{{ if .ImportErrors }}<br>import errors: {{ .ImportErrors }}{{ end }}{{ if .Snapshot }}<br>snapshot: {{ .Snapshot }}{{ end }}{{ if .RestoredDatabases }}<br>restored: {{ range $i, $database := .RestoredDatabases }}{{ if $i }}, {{ end }}{{ $database }}{{ end }}{{ end }}</td>
         </tr>
         {{- end }}
       </tbody>
@@ -54,4 +58,27 @@ var HTML_REPORT = `
     <a href="https://github.com/MaxxtonGroup/backup-validator">Backup Validator</a>
   </div>
 </body>
+{{- define "assertResults" }}
+            {{- if or .AssertResults .FailedAsserts }}
+            <ul style="font-size: 12px; margin: 0; padding-left: 20px;">
+              {{- if .AssertResults }}
+              {{- range .AssertResults }}
+              <li>{{ .Type }}: {{ .Status }} ({{ .Duration }}){{ if .Message }} - {{ .Message }}{{ end }}{{ if .Expected }}<br>expected: {{ .Expected }}, actual: {{ .Actual }}{{ end }}
+                {{- if .Details }}
+                <ul style="margin: 0; padding-left: 15px;">
+                  {{- range .Details }}
+                  <li>{{ .Database }}: {{ .Status }}{{ if .Message }} - {{ .Message }}{{ end }}{{ if .Expected }} (expected: {{ .Expected }}, actual: {{ .Actual }}){{ end }}</li>
+                  {{- end }}
+                </ul>
+                {{- end }}
+              </li>
+              {{- end }}
+              {{- else }}
+              {{- range .FailedAsserts }}
+              <li>{{ . }}</li>
+              {{- end }}
+              {{- end }}
+            </ul>
+            {{- end }}
+{{- end }}
 `
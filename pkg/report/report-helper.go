@@ -7,6 +7,7 @@ import (
 	"text/template"
 	"time"
 
+	"github.com/MaxxtonGroup/backup-validator/pkg/assert"
 	"github.com/MaxxtonGroup/backup-validator/pkg/validator"
 )
 
@@ -16,12 +17,28 @@ type TemplateReport struct {
 }
 
 type TemplateTestResult struct {
-	Name            string
-	TotalDuration   string
-	RestoreDuration string
-	ImportDuration  string
-	Error           *string
-	FailedAsserts   []string
+	Name              string
+	TotalDuration     string
+	RestoreDuration   string
+	ImportDuration    string
+	Error             *string
+	FailedAsserts     []string
+	AssertResults     []*TemplateAssertResult
+	Warnings          int
+	RecoveryPoint     *string
+	ImportErrors      *int
+	Snapshot          *string
+	RestoredDatabases []string
+}
+
+type TemplateAssertResult struct {
+	Type     string
+	Status   assert.Status
+	Message  string
+	Expected string
+	Actual   string
+	Details  []*assert.Detail
+	Duration string
 }
 
 func StoreJsonReport(reportFile string, testResults []*validator.TestResult) error {
@@ -55,12 +72,33 @@ func StoreHtmlReport(reportFile string, testResults []*validator.TestResult) err
 	templateTestResults := make([]*TemplateTestResult, 0)
 	for _, result := range testResults {
 		templateResult := TemplateTestResult{
-			Name:            result.Name,
-			TotalDuration:   result.TotalDuration.Round(time.Second).String(),
-			RestoreDuration: result.RestoreDuration.Round(time.Second).String(),
-			ImportDuration:  result.ImportDuration.Round(time.Second).String(),
-			Error:           result.Error,
-			FailedAsserts:   result.FailedAsserts,
+			Name:              result.Name,
+			TotalDuration:     result.TotalDuration.Round(time.Second).String(),
+			RestoreDuration:   result.RestoreDuration.Round(time.Second).String(),
+			ImportDuration:    result.ImportDuration.Round(time.Second).String(),
+			Error:             result.Error,
+			FailedAsserts:     result.FailedAsserts,
+			ImportErrors:      result.ImportErrors,
+			Snapshot:          result.Snapshot,
+			RestoredDatabases: result.RestoredDatabases,
+		}
+		for _, assertResult := range result.AssertResults {
+			if assertResult.Status == assert.StatusWarn {
+				templateResult.Warnings++
+			}
+			templateResult.AssertResults = append(templateResult.AssertResults, &TemplateAssertResult{
+				Type:     assertResult.Type,
+				Status:   assertResult.Status,
+				Message:  assertResult.Message,
+				Expected: assertResult.Expected,
+				Actual:   assertResult.Actual,
+				Details:  assertResult.Details,
+				Duration: assertResult.Duration.Round(time.Millisecond).String(),
+			})
+		}
+		if result.RecoveryPoint != nil && result.RecoveryPoint.Time != nil {
+			recoveryPoint := result.RecoveryPoint.Time.Format(time.RFC3339)
+			templateResult.RecoveryPoint = &recoveryPoint
 		}
 		templateTestResults = append(templateTestResults, &templateResult)
 	}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 )
@@ -14,6 +15,8 @@ import (
 type DockerConfig struct {
 	Image       string   `yaml:"image"`
 	Environment []string `yaml:"environment"`
+	Command     []string `yaml:"command"`
+	Ports       []string `yaml:"ports"`
 	ReadyCheck  []string `yaml:"readyCheck"`
 	DumpLogs    bool     `yaml:"dumpLogs"`
 }
@@ -62,7 +65,14 @@ func (p DockerRuntimeProvider) Setup(testName string, dir string) error {
 			args = append(args, "-e", env)
 		}
 	}
+	for _, port := range p.dockerConfig.Ports {
+		// Publish on a random port of the loopback interface, see GetAddress
+		args = append(args, "--publish=127.0.0.1::"+port)
+	}
 	args = append(args, p.dockerConfig.Image)
+	if p.dockerConfig.Command != nil {
+		args = append(args, p.dockerConfig.Command...)
+	}
 	log.Printf("Run: docker %s", strings.Join(args, " "))
 	cmd := exec.Command("docker", args...)
 
@@ -142,15 +152,23 @@ func (p DockerRuntimeProvider) Destroy(testName string, dir string) error {
 }
 
 func (p DockerRuntimeProvider) Exec(testName string, command string, args ...string) (*string, error) {
-	return p.execAsUser(testName, nil, command, args...)
+	return p.execAsUser(testName, nil, nil, command, args...)
 }
 
 func (p DockerRuntimeProvider) ExecRoot(testName string, command string, args ...string) (*string, error) {
 	rootUID := "0"
-	return p.execAsUser(testName, &rootUID, command, args...)
+	return p.execAsUser(testName, &rootUID, nil, command, args...)
+}
+
+func (p DockerRuntimeProvider) ExecAsUser(testName string, user string, command string, args ...string) (*string, error) {
+	return p.execAsUser(testName, &user, nil, command, args...)
+}
+
+func (p DockerRuntimeProvider) ExecWithEnv(testName string, env map[string]string, command string, args ...string) (*string, error) {
+	return p.execAsUser(testName, nil, env, command, args...)
 }
 
-func (p DockerRuntimeProvider) execAsUser(testName string, uid *string, command string, args ...string) (*string, error) {
+func (p DockerRuntimeProvider) execAsUser(testName string, uid *string, env map[string]string, command string, args ...string) (*string, error) {
 	if p.runtime.containerID == nil {
 		return nil, fmt.Errorf("[%s] Docker Container isn't created", testName)
 	}
@@ -160,9 +178,24 @@ func (p DockerRuntimeProvider) execAsUser(testName string, uid *string, command
 	if uid != nil {
 		cmdArgs = append(cmdArgs, "-u", *uid)
 	}
+	// Only the names are passed to docker, it copies the values from its own environment
+	envNames := []string{}
+	for name := range env {
+		envNames = append(envNames, name)
+	}
+	sort.Strings(envNames)
+	for _, name := range envNames {
+		cmdArgs = append(cmdArgs, "-e", name)
+	}
 	cmdArgs = append(cmdArgs, *p.runtime.containerID, command)
 	// log.Printf("[%s] exec: docker %s\n", testName, strings.Join(append(cmdArgs, args...), " "))
 	cmd := exec.Command("docker", append(cmdArgs, args...)...)
+	if len(env) > 0 {
+		cmd.Env = os.Environ()
+		for _, name := range envNames {
+			cmd.Env = append(cmd.Env, name+"="+env[name])
+		}
+	}
 
 	// run command
 	stderr, err := cmd.StderrPipe()
@@ -197,12 +230,41 @@ func (p DockerRuntimeProvider) execAsUser(testName string, uid *string, command
 		if len(errOutput) > 0 {
 			log.Printf("[%s] exec: %s", testName, errOutput)
 		}
-		return nil, fmt.Errorf("command [%s %s] failed: %s", "docker", strings.Join(append(cmdArgs, args...), " "), err)
+		exitCode := -1
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			exitCode = exitErr.ExitCode()
+		}
+		return nil, &ExecError{
+			Command:  "docker " + strings.Join(append(cmdArgs, args...), " "),
+			Stdout:   string(stdOutSlurp),
+			Stderr:   string(stdErrSlurp),
+			ExitCode: exitCode,
+			Err:      err,
+		}
 	}
 	output := string(stdOutSlurp)
 	return &output, nil
 }
 
+// GetAddress returns the host address of a published container port, eg. 127.0.0.1:49153
+func (p DockerRuntimeProvider) GetAddress(testName string, port string) (*string, error) {
+	if p.runtime.containerID == nil {
+		return nil, fmt.Errorf("[%s] Docker Container isn't created", testName)
+	}
+	if !strings.Contains(port, "/") {
+		port += "/tcp"
+	}
+	output, err := exec.Command("docker", "port", *p.runtime.containerID, port).Output()
+	if err != nil {
+		return nil, fmt.Errorf("[%s] Port %s of the Docker Container isn't published: %s", testName, port, err)
+	}
+	address := strings.TrimSpace(strings.Split(string(output), "\n")[0])
+	if address == "" {
+		return nil, fmt.Errorf("[%s] Port %s of the Docker Container isn't published", testName, port)
+	}
+	return &address, nil
+}
+
 func NewDockerRuntimeProvider(dockerConfig DockerConfig) DockerRuntimeProvider {
 	runtime := Runtime{}
 	dockerRuntimeProvider := DockerRuntimeProvider{
@@ -1,8 +1,31 @@
 package runtime
 
+import "fmt"
+
 type RuntimeProvider interface {
 	Setup(testName string, dir string) error
 	Destroy(testName string, dir string) error
 	Exec(testName string, command string, args ...string) (*string, error)
 	ExecRoot(testName string, command string, args ...string) (*string, error)
+	ExecAsUser(testName string, user string, command string, args ...string) (*string, error)
+	// ExecWithEnv runs the command with environment variables, the values aren't part of the command line (eg. passwords)
+	ExecWithEnv(testName string, env map[string]string, command string, args ...string) (*string, error)
+	GetAddress(testName string, port string) (*string, error)
+}
+
+// ExecError is returned when a command in the runtime exits with an error, it keeps the output for inspection
+type ExecError struct {
+	Command  string
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Err      error
+}
+
+func (e *ExecError) Error() string {
+	return fmt.Sprintf("command [%s] failed: %s", e.Command, e.Err)
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
 }
@@ -0,0 +1,135 @@
+package schema
+
+import (
+	"fmt"
+	"io/ioutil"
+	"sort"
+
+	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/ghodss/yaml"
+)
+
+// Snapshot is the schema of the databases in a restored backup, stored as golden file to detect schema drift
+type Snapshot struct {
+	Databases map[string]format.DatabaseSchema `json:"databases"`
+}
+
+// Take reads the schema of the databases from the format provider
+func Take(testName string, formatProvider format.FormatProvider, databases []string) (*Snapshot, error) {
+	schemaProvider, ok := formatProvider.(format.SchemaProvider)
+	if !ok {
+		return nil, fmt.Errorf("[%s] format doesn't support schemas", testName)
+	}
+	snapshot := &Snapshot{
+		Databases: map[string]format.DatabaseSchema{},
+	}
+	for _, database := range databases {
+		databaseSchema, err := schemaProvider.GetSchema(testName, database)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Databases[database] = databaseSchema
+	}
+	return snapshot, nil
+}
+
+// Load reads a snapshot from a yaml (or json) file
+func Load(file string) (*Snapshot, error) {
+	bytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{}
+	err = yaml.Unmarshal(bytes, snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid schema file %s: %s", file, err)
+	}
+	return snapshot, nil
+}
+
+// Store writes the snapshot to a yaml file
+func Store(file string, snapshot *Snapshot) error {
+	bytes, err := yaml.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, bytes, 0644)
+}
+
+// Diff compares the schema of a database with the expected schema, it returns a line per difference:
+// '-' for missing tables and fields, '+' for extra tables and fields and '~' for changed types. Optional fields are skipped.
+func Diff(expected format.DatabaseSchema, actual format.DatabaseSchema, allowExtra bool) []string {
+	diff := []string{}
+	for _, table := range tableNames(expected, actual) {
+		expectedFields, expectedTable := expected[table]
+		actualFields, actualTable := actual[table]
+		if !actualTable {
+			diff = append(diff, "- "+table)
+			continue
+		}
+		if !expectedTable {
+			if !allowExtra {
+				diff = append(diff, "+ "+table)
+			}
+			continue
+		}
+
+		for _, field := range fieldNames(expectedFields, actualFields) {
+			expectedType, expectedField := expectedFields[field]
+			actualType, actualField := actualFields[field]
+			if !actualField {
+				if expectedType != format.OptionalField {
+					diff = append(diff, "- "+table+"."+field+formatType(expectedType))
+				}
+			} else if !expectedField {
+				if !allowExtra && actualType != format.OptionalField {
+					diff = append(diff, "+ "+table+"."+field+formatType(actualType))
+				}
+			} else if isComparableType(expectedType) && isComparableType(actualType) && expectedType != actualType {
+				diff = append(diff, fmt.Sprintf("~ %s.%s: %s -> %s", table, field, expectedType, actualType))
+			}
+		}
+	}
+	return diff
+}
+
+// isComparableType checks if the type is known, optional fields don't have a type
+func isComparableType(fieldType string) bool {
+	return fieldType != "" && fieldType != format.OptionalField
+}
+
+func formatType(fieldType string) string {
+	if fieldType == "" {
+		return ""
+	}
+	return " (" + fieldType + ")"
+}
+
+func tableNames(schemas ...format.DatabaseSchema) []string {
+	keySet := map[string]bool{}
+	for _, schema := range schemas {
+		for table := range schema {
+			keySet[table] = true
+		}
+	}
+	return sortKeys(keySet)
+}
+
+func fieldNames(fieldMaps ...map[string]string) []string {
+	keySet := map[string]bool{}
+	for _, fields := range fieldMaps {
+		for field := range fields {
+			keySet[field] = true
+		}
+	}
+	return sortKeys(keySet)
+}
+
+func sortKeys(keySet map[string]bool) []string {
+	keys := []string{}
+	for key := range keySet {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -3,7 +3,9 @@ package validator
 import (
 	"github.com/MaxxtonGroup/backup-validator/pkg/assert"
 	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+	"github.com/MaxxtonGroup/backup-validator/pkg/elasticsearch"
 	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/MaxxtonGroup/backup-validator/pkg/plugin"
 	"github.com/MaxxtonGroup/backup-validator/pkg/runtime"
 )
 
@@ -17,9 +19,19 @@ type TestConfig struct {
 
 	Restic                          *backup.ResticConfig                    `yaml:"restic"`
 	ElasticsearchSnapshotRepository *format.ElasticsearchSnapshotRepository `yaml:"elasticsearchSnapshotRepository"`
+	Elasticsearch                   *elasticsearch.Config                   `yaml:"elasticsearch"`
+	Mongo                           *format.MongoConfig                     `yaml:"mongo"`
+	Postgresql                      *format.PostgresqlConfig                `yaml:"postgresql"`
+	Sqlite                          *format.SqliteConfig                    `yaml:"sqlite"`
+	Etcd                            *format.EtcdConfig                      `yaml:"etcd"`
+	Vault                           *format.VaultConfig                     `yaml:"vault"`
+	Clickhouse                      *format.ClickhouseConfig                `yaml:"clickhouse"`
+	FormatPlugin                    *plugin.Config                          `yaml:"formatPlugin"`
+	BackupPlugin                    *plugin.Config                          `yaml:"backupPlugin"`
 	Asserts                         *[]assert.AssertConfig                  `yaml:"asserts"`
 	Docker                          *runtime.DockerConfig                   `yaml:"docker"`
 	ImportOptions                   *[]string                               `yaml:"importOptions"`
+	MaxImportErrors                 *int                                    `yaml:"maxImportErrors"`
 }
 
 type DockerConfig struct {
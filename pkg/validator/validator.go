@@ -1,30 +1,46 @@
 package validator
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/MaxxtonGroup/backup-validator/pkg/assert"
 	"github.com/MaxxtonGroup/backup-validator/pkg/runtime"
+	"github.com/MaxxtonGroup/backup-validator/pkg/schema"
 
 	"github.com/MaxxtonGroup/backup-validator/pkg/backup"
+	"github.com/MaxxtonGroup/backup-validator/pkg/elasticsearch"
 
 	"github.com/MaxxtonGroup/backup-validator/pkg/format"
+	"github.com/MaxxtonGroup/backup-validator/pkg/pattern"
+	"github.com/MaxxtonGroup/backup-validator/pkg/plugin"
 	"github.com/ghodss/yaml"
 )
 
 type TestResult struct {
-	Name            string        `json:"name"`
-	TotalDuration   time.Duration `json:"totalDuration"`
-	RestoreDuration time.Duration `json:"restoreDuration"`
-	ImportDuration  time.Duration `json:"importDuration"`
-	Error           *string       `json:"error"`
-	FailedAsserts   []string      `json:"failedAsserts"`
+	Name              string                  `json:"name"`
+	TotalDuration     time.Duration           `json:"totalDuration"`
+	RestoreDuration   time.Duration           `json:"restoreDuration"`
+	ImportDuration    time.Duration           `json:"importDuration"`
+	Error             *string                 `json:"error"`
+	FailedAsserts     []string                `json:"failedAsserts"`
+	AssertResults     []*assert.Result        `json:"assertResults"`
+	RecoveryPoint     *format.RecoveryPoint   `json:"recoveryPoint"`
+	ImportErrors      *int                    `json:"importErrors"`
+	Snapshot          *string                 `json:"snapshot"`
+	RestoredDatabases []string                `json:"restoredDatabases"`
+	History           []*assert.MetricsRecord `json:"history"`
 }
 
+// maxHistory is the amount of runs of which the metrics are kept in the report
+const maxHistory = 30
+
 var asserts = []assert.Assert{
 	assert.NewFilesExistsAssert(),
 	assert.NewFileModifiedAssert(),
@@ -34,10 +50,23 @@ var asserts = []assert.Assert{
 	assert.NewDatabasesExistsAssert(),
 	assert.NewDatabasesSizeAssert(),
 	assert.NewTablesExistsAssert(),
+	assert.NewRecoveryPointAssert(),
+	assert.NewRowCountAssert(),
+	assert.NewGrowthAssert(),
+	assert.NewSeriesExistsAssert(),
+	assert.NewIndexHealthAssert(),
+	assert.NewIndexMappingsAssert(),
+	assert.NewMongoValidateAssert(),
+	assert.NewIndexesExistAssert(),
+	assert.NewPostgresIntegrityAssert(),
+	assert.NewSchemaMatchesAssert(),
+	assert.NewExecAssert(),
+	assert.NewPluginAssert(),
+	assert.NewRepositoryIntegrityAssert(),
 }
 
-// Validate backups based on tests specified in the configFiles
-func Validate(configFiles []string, cleanup bool) ([]*TestResult, error) {
+// Validate backups based on tests specified in the configFiles, the metrics of previousResults are used to compare with previous runs
+func Validate(configFiles []string, cleanup bool, previousResults []*TestResult) ([]*TestResult, error) {
 	// Load config files
 	configs, err := loadConfig(configFiles)
 	if err != nil {
@@ -57,7 +86,13 @@ func Validate(configFiles []string, cleanup bool) ([]*TestResult, error) {
 				// Run test
 				log.Printf("[%s] Validate backup (running)\n", test.Name)
 				startTime := time.Now()
-				result, err := validateBackup(&test, cleanup)
+				var history []*assert.MetricsRecord
+				for _, previousResult := range previousResults {
+					if previousResult.Name == test.Name {
+						history = previousResult.History
+					}
+				}
+				result, err := validateBackup(&test, cleanup, history, nil)
 				result.TotalDuration = time.Since(startTime)
 
 				// Collect result
@@ -76,9 +111,66 @@ func Validate(configFiles []string, cleanup bool) ([]*TestResult, error) {
 	return results, nil
 }
 
-func validateBackup(test *TestConfig, cleanup bool) (*TestResult, error) {
+// SnapshotSchema restores the latest backup of the test and returns the schema of the databases that match the pattern
+func SnapshotSchema(configFiles []string, testName string, databasePattern string, cleanup bool) (*schema.Snapshot, error) {
+	configs, err := loadConfig(configFiles)
+	if err != nil {
+		return nil, err
+	}
+	tests := []TestConfig{}
+	for _, config := range configs {
+		if config.Tests != nil {
+			for _, test := range *config.Tests {
+				if testName == "" || test.Name == testName {
+					tests = append(tests, test)
+				}
+			}
+		}
+	}
+	if len(tests) == 0 {
+		return nil, fmt.Errorf("Test '%s' not found", testName)
+	}
+	if len(tests) > 1 {
+		return nil, fmt.Errorf("Multiple tests found, use --test=<name> to select one")
+	}
+
+	// Only restore the backup, the asserts are skipped
+	test := tests[0]
+	test.Asserts = nil
+	var snapshot *schema.Snapshot
+	_, err = validateBackup(&test, cleanup, nil, func(formatProvider format.FormatProvider) error {
+		databases, err := formatProvider.ListDatabases(test.Name)
+		if err != nil {
+			return err
+		}
+		matchingDatabases, err := pattern.Match(databasePattern, databases)
+		if err != nil {
+			return err
+		}
+		snapshot, err = schema.Take(test.Name, formatProvider, matchingDatabases)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// validateBackup restores the backup and runs the asserts, inspect is called with the restored data before the asserts run
+func validateBackup(test *TestConfig, cleanup bool, history []*assert.MetricsRecord, inspect func(formatProvider format.FormatProvider) error) (*TestResult, error) {
 	result := &TestResult{
-		Name: test.Name,
+		Name:    test.Name,
+		History: history,
+	}
+
+	// Check the severities before the backup is restored, which may take hours
+	var severities []string
+	if test.Asserts != nil {
+		var err error
+		severities, err = getSeverities(*test.Asserts)
+		if err != nil {
+			return result, err
+		}
 	}
 
 	// create workdir
@@ -101,12 +193,19 @@ func validateBackup(test *TestConfig, cleanup bool) (*TestResult, error) {
 	if err != nil {
 		return result, err
 	}
+	// Plugins are stopped after the test
+	if closer, ok := backupProvider.(io.Closer); ok {
+		defer closer.Close()
+	}
 
 	// Find format provider
 	formatProvider, err := getFormatProvider(test.Format, runtimeProvider, test)
 	if err != nil {
 		return result, err
 	}
+	if closer, ok := formatProvider.(io.Closer); ok {
+		defer closer.Close()
+	}
 
 	// Destory format provider
 	if cleanup {
@@ -135,16 +234,38 @@ func validateBackup(test *TestConfig, cleanup bool) (*TestResult, error) {
 		return result, fmt.Errorf("no snapshots found")
 	}
 	snapshot := snapshots[len(snapshots)-1]
+	result.Snapshot = &snapshot.Name
+	log.Printf("[%s] Selected snapshot %s of %s", test.Name, snapshot.Name, snapshot.Time.Format(time.RFC3339))
 
 	if test.ImportOptions == nil {
 		importOptions := []string{}
 		test.ImportOptions = &importOptions
 	}
 
+	// Render the templates in the import options and asserts, eg. {{ .SnapshotTime | date "2006.01.02" }}
+	patternData := pattern.NewData(test.Name, snapshot.Time)
+	importOptions := *test.ImportOptions
+	err = pattern.RenderConfig(&importOptions, patternData)
+	if err != nil {
+		return result, err
+	}
+	var assertConfigs []assert.AssertConfig
+	if test.Asserts != nil {
+		assertConfigs = *test.Asserts
+		err = pattern.RenderConfig(&assertConfigs, patternData)
+		if err != nil {
+			return result, err
+		}
+	}
+
 	// Restore backup
 	restoreStartTime := time.Now()
-	err = backupProvider.Restore(test.Name, dir, snapshot, *test.ImportOptions)
+	err = backupProvider.Restore(test.Name, dir, snapshot, importOptions)
 	result.RestoreDuration = time.Since(restoreStartTime)
+	result.RestoredDatabases = snapshot.RestoredDatabases
+	if receiver, ok := formatProvider.(format.RestoredDatabasesReceiver); ok {
+		receiver.SetRestoredDatabases(test.Name, snapshot.RestoredDatabases)
+	}
 	if err != nil {
 		return result, err
 	}
@@ -152,68 +273,320 @@ func validateBackup(test *TestConfig, cleanup bool) (*TestResult, error) {
 	// Import backup data in format provider
 	log.Printf("[%s] Importing data...\n", test.Name)
 	importStartTime := time.Now()
-	err = formatProvider.ImportData(test.Name, dir, *test.ImportOptions)
+	err = formatProvider.ImportData(test.Name, dir, importOptions)
 	result.ImportDuration = time.Since(importStartTime)
+	var importErr *format.ImportError
+	if errors.As(err, &importErr) {
+		result.ImportErrors = &importErr.Errors
+		for _, msg := range importErr.Messages {
+			log.Printf("[%s] Import error: %s", test.Name, msg)
+		}
+		if test.MaxImportErrors != nil && importErr.Errors <= *test.MaxImportErrors {
+			log.Printf("[%s] Ignoring %d import errors (maxImportErrors: %d)", test.Name, importErr.Errors, *test.MaxImportErrors)
+			err = nil
+		}
+	}
 	if err != nil {
 		return result, err
 	}
 
+	// Find the point in time the data was recovered to
+	if recoveryPointProvider, ok := formatProvider.(format.RecoveryPointProvider); ok {
+		result.RecoveryPoint, err = recoveryPointProvider.GetRecoveryPoint(test.Name)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	if inspect != nil {
+		err = inspect(formatProvider)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	// Measure the restored backup, only the metrics that are compared by a growth assert
+	growthMetrics := []string{}
+	for _, assertConfig := range assertConfigs {
+		if assertConfig.Growth != nil {
+			growthMetrics = append(growthMetrics, assertConfig.Growth.Metric)
+		}
+	}
+	metricsRecord := &assert.MetricsRecord{
+		Time:     time.Now(),
+		Snapshot: snapshot.Name,
+		Metrics:  assert.Metrics{},
+	}
+	if len(growthMetrics) > 0 {
+		metricsRecord.Metrics = collectMetrics(test.Name, dir, formatProvider, growthMetrics)
+	}
+	assertHistory := &assert.History{
+		Current:  metricsRecord.Metrics,
+		Previous: history,
+	}
+
 	// Validate
-	if test.Asserts != nil {
+	if assertConfigs != nil {
 		timings := assert.Timings{
 			RestoreTime: result.RestoreDuration,
 			ImportTime:  result.ImportDuration,
 		}
-		failedAsserts := []string{}
-		for _, assertConfig := range *test.Asserts {
-			for _, assert := range asserts {
-				if assert.RunFor(&assertConfig) {
-					msg := assert.Run(test.Name, dir, &assertConfig, backupProvider, formatProvider, timings, snapshot)
-					if msg != nil {
-						failedAsserts = append(failedAsserts, *msg)
+		// Growth asserts compare the measurements of the whole run (eg. of the rowCount asserts), so they run last
+		resultsPerConfig := make([][]*assert.Result, len(assertConfigs))
+		for _, growthPass := range []bool{false, true} {
+			for i, assertConfig := range assertConfigs {
+				severity := severities[i]
+				for _, a := range asserts {
+					if _, isGrowth := a.(assert.GrowthAssert); isGrowth != growthPass || !a.RunFor(&assertConfig) {
+						continue
+					}
+					startTime := time.Now()
+					assertResult := a.Run(test.Name, dir, &assertConfig, backupProvider, formatProvider, timings, snapshot, assertHistory)
+					assertResult.Type = a.Type()
+					assertResult.Severity = severity
+					assertResult.Duration = time.Since(startTime)
+
+					// Non-critical asserts only warn, they don't fail the test
+					if severity == "warning" && assertResult.Failed() {
+						assertResult.Status = assert.StatusWarn
 					}
+					log.Printf("[%s] Assert %s", test.Name, assertResult)
+					resultsPerConfig[i] = append(resultsPerConfig[i], assertResult)
+				}
+			}
+		}
+
+		failedAsserts := []string{}
+		assertResults := []*assert.Result{}
+		for _, configResults := range resultsPerConfig {
+			for _, assertResult := range configResults {
+				if assertResult.Failed() {
+					failedAsserts = append(failedAsserts, assertResult.Message)
 				}
+				assertResults = append(assertResults, assertResult)
 			}
 		}
 		result.FailedAsserts = failedAsserts
+		result.AssertResults = assertResults
+	}
+
+	// Only the measurements of passing runs are kept, a broken backup shouldn't become the baseline of the next runs
+	if len(result.FailedAsserts) == 0 && len(metricsRecord.Metrics) > 0 {
+		result.History = append(result.History, metricsRecord)
+		if len(result.History) > maxHistory {
+			result.History = result.History[len(result.History)-maxHistory:]
+		}
 	}
 
 	return result, nil
 }
 
+// getSeverities returns the severity of every assert, error when it isn't set
+func getSeverities(assertConfigs []assert.AssertConfig) ([]string, error) {
+	severities := []string{}
+	for _, assertConfig := range assertConfigs {
+		severity := "error"
+		if assertConfig.Severity != nil {
+			severity = *assertConfig.Severity
+		}
+		if severity != "error" && severity != "warning" {
+			return nil, fmt.Errorf("Unsupported assert severity '%s', should be one of: \"error\" or \"warning\"", severity)
+		}
+		severities = append(severities, severity)
+	}
+	return severities, nil
+}
+
+// collectMetrics measures the restored files and the databases that match one of the metric patterns, these are compared with previous runs by the growth assert
+func collectMetrics(testName string, dir string, formatProvider format.FormatProvider, metricPatterns []string) assert.Metrics {
+	metrics := assert.Metrics{}
+	isCompared := func(name string) bool {
+		for _, metricPattern := range metricPatterns {
+			matches, err := pattern.Match(metricPattern, []string{name})
+			if err == nil && len(matches) > 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Files restored by the backup provider
+	if isCompared("fileCount") || isCompared("restoredBytes") {
+		fileCount := 0
+		restoredBytes := int64(0)
+		err := filepath.Walk(filepath.Join(dir, "workdir"), func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				fileCount++
+				restoredBytes += info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			log.Printf("[%s] Failed to measure the restored files: %s", testName, err)
+		} else {
+			metrics["fileCount"] = float64(fileCount)
+			metrics["restoredBytes"] = float64(restoredBytes)
+		}
+	}
+
+	// Database sizes, not every format supports this
+	databases, err := formatProvider.ListDatabases(testName)
+	if err != nil {
+		log.Printf("[%s] Failed to list the databases to measure: %s", testName, err)
+		return metrics
+	}
+	for _, database := range databases {
+		name := "databaseSize:" + database
+		if !isCompared(name) {
+			continue
+		}
+		size, err := formatProvider.GetDatabaseSize(testName, database)
+		if err != nil {
+			log.Printf("[%s] Failed to measure %s: %s", testName, name, err)
+			continue
+		}
+		metrics[name] = float64(*size)
+	}
+	return metrics
+}
+
 func getFormatProvider(formatType string, runtimeProvider runtime.RuntimeProvider, test *TestConfig) (format.FormatProvider, error) {
 	switch formatType {
 	case "file":
 		formatProvider := format.NewFileFormatProvider()
 		return formatProvider, nil
 	case "mongo":
-		formatProvider := format.NewMongoFormatProvider(runtimeProvider)
+		mongoConfig := format.MongoConfig{}
+		if test.Mongo != nil {
+			mongoConfig = *test.Mongo
+		}
+		formatProvider := format.NewMongoFormatProvider(runtimeProvider, mongoConfig)
 		return formatProvider, nil
 	case "postgresql":
-		formatProvider := format.NewPostgresqlFormatProvider(runtimeProvider)
+		postgresqlConfig := format.PostgresqlConfig{}
+		if test.Postgresql != nil {
+			postgresqlConfig = *test.Postgresql
+		}
+		formatProvider := format.NewPostgresqlFormatProvider(runtimeProvider, postgresqlConfig)
+		return formatProvider, nil
+	case "sqlite":
+		if test.Sqlite == nil {
+			return nil, fmt.Errorf("Missing 'sqlite' config for the sqlite format")
+		}
+		formatProvider := format.NewSqliteFormatProvider(*test.Sqlite)
 		return formatProvider, nil
-	case "elasticsearch":
-		formatProvider := format.NewElasticsearchFormatProvider(runtimeProvider, *test.ElasticsearchSnapshotRepository)
+	case "redis":
+		formatProvider := format.NewRedisFormatProvider(runtimeProvider)
+		return formatProvider, nil
+	case "etcd":
+		etcdConfig := format.EtcdConfig{}
+		if test.Etcd != nil {
+			etcdConfig = *test.Etcd
+		}
+		formatProvider := format.NewEtcdFormatProvider(runtimeProvider, etcdConfig)
+		return formatProvider, nil
+	case "vault-raft":
+		if test.Vault == nil {
+			return nil, fmt.Errorf("Missing 'vault' config for the vault-raft format")
+		}
+		formatProvider := format.NewVaultRaftFormatProvider(runtimeProvider, *test.Vault)
+		return formatProvider, nil
+	case "clickhouse":
+		clickhouseConfig := format.ClickhouseConfig{}
+		if test.Clickhouse != nil {
+			clickhouseConfig = *test.Clickhouse
+		}
+		formatProvider := format.NewClickhouseFormatProvider(runtimeProvider, clickhouseConfig)
+		return formatProvider, nil
+	case "prometheus":
+		formatProvider := format.NewPrometheusFormatProvider(runtimeProvider)
+		return formatProvider, nil
+	case "influxdb":
+		formatProvider := format.NewInfluxdbFormatProvider(runtimeProvider)
+		return formatProvider, nil
+	case "plugin":
+		if test.FormatPlugin == nil {
+			return nil, fmt.Errorf("Missing 'formatPlugin' config for the plugin format")
+		}
+		return plugin.NewFormatProvider(test.Name, *test.FormatPlugin)
+	case "elasticsearch", "opensearch":
+		if test.ElasticsearchSnapshotRepository == nil {
+			return nil, fmt.Errorf("Missing 'elasticsearchSnapshotRepository' config for the %s format", formatType)
+		}
+		flavour, client, err := getElasticsearchClient(test, runtimeProvider)
+		if err != nil {
+			return nil, err
+		}
+		formatProvider := format.NewElasticsearchFormatProvider(runtimeProvider, *test.ElasticsearchSnapshotRepository, getElasticsearchConfig(test).GetRepository(), *flavour, client)
 		return formatProvider, nil
 	}
 	return nil, fmt.Errorf("Unsupported format '%s'", formatType)
 }
 
 func getBackupProvider(test *TestConfig, runtimeProvider runtime.RuntimeProvider) (backup.BackupProvider, error) {
+	if test.BackupPlugin != nil {
+		return plugin.NewBackupProvider(test.Name, *test.BackupPlugin)
+	}
 	if test.Restic != nil {
 		backupProvider := backup.NewResticBackupProvider(*test.Restic)
 		return backupProvider, nil
 	}
 	if test.ElasticsearchSnapshotRepository != nil {
-		backupProvider := backup.NewElasticsearchBackupProvider(runtimeProvider)
+		_, client, err := getElasticsearchClient(test, runtimeProvider)
+		if err != nil {
+			return nil, err
+		}
+		config := getElasticsearchConfig(test)
+		snapshotConfig := elasticsearch.SnapshotConfig{}
+		if config.Snapshot != nil {
+			snapshotConfig = *config.Snapshot
+		}
+		backupProvider := backup.NewElasticsearchBackupProvider(client, config.GetRepository(), snapshotConfig)
 		return backupProvider, nil
 	}
 	return nil, fmt.Errorf("No backup config found")
 }
 
+func getElasticsearchConfig(test *TestConfig) elasticsearch.Config {
+	config := elasticsearch.Config{}
+	if test.Elasticsearch != nil {
+		config = *test.Elasticsearch
+	}
+	if config.Flavour == nil && test.Format == "opensearch" {
+		flavour := "opensearch"
+		config.Flavour = &flavour
+	}
+	return config
+}
+
+func getElasticsearchClient(test *TestConfig, runtimeProvider runtime.RuntimeProvider) (*elasticsearch.Flavour, *elasticsearch.Client, error) {
+	config := getElasticsearchConfig(test)
+	environment := []string{}
+	if test.Docker != nil {
+		environment = test.Docker.Environment
+	}
+	flavour, err := elasticsearch.NewFlavour(config, environment)
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := elasticsearch.NewClient(runtimeProvider, *flavour)
+	if err != nil {
+		return nil, nil, err
+	}
+	return flavour, client, nil
+}
+
 func getRuntimeProvider(test *TestConfig) (runtime.RuntimeProvider, error) {
 	if test.Docker != nil {
-		runtimeProvider := runtime.NewDockerRuntimeProvider(*test.Docker)
+		dockerConfig := *test.Docker
+		if test.ElasticsearchSnapshotRepository != nil && (test.Elasticsearch == nil || test.Elasticsearch.Url == nil) {
+			// The elasticsearch client connects to the published port of the container
+			dockerConfig.Ports = append(append([]string{}, dockerConfig.Ports...), elasticsearch.Port)
+		}
+		runtimeProvider := runtime.NewDockerRuntimeProvider(dockerConfig)
 		return runtimeProvider, nil
 	}
 	return nil, nil